@@ -35,7 +35,7 @@ func NewBuildConfigsCmd() *cobra.Command {
 	return cmd
 }
 
-func executeConfigs(_ *cobra.Command, _ []string) error {
+func executeConfigs(c *cobra.Command, _ []string) error {
 	options := build.Options{
 		Options:        root.LoadRootOptions(),
 		SkipExisting:   viper.GetBool("skip-existing"),
@@ -43,5 +43,6 @@ func executeConfigs(_ *cobra.Command, _ []string) error {
 		IgnoreErrors:   viper.GetBool("ignore-errors"),
 		RedirectErrors: viper.GetString("redirect-errors"),
 	}
-	return build.Run(options)
+	err := build.Run(c.Context(), options)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
@@ -29,6 +29,8 @@ func NewCleanupConfigsCmd() *cobra.Command {
 	return cmd
 }
 
-func executeConfigs(c *cobra.Command, args []string) error {
-	return cleanup.Run(cleanup.Options{Options: root.LoadRootOptions()}, cleanup.NewFileCleaner())
+func executeConfigs(c *cobra.Command, _ []string) error {
+	options := cleanup.Options{Options: root.LoadRootOptions()}
+	err := cleanup.Run(c.Context(), options, cleanup.NewFileCleaner())
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
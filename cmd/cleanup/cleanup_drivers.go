@@ -25,14 +25,18 @@ func NewCleanupDriversCmd() *cobra.Command {
 		Use:   "cleanup",
 		Short: "Cleanup desired remote drivers",
 		RunE:  executeDrivers,
+		// Driver version selectors are resolved against the bucket
+		Annotations: map[string]string{root.DriverVersionSourceAnnotation: root.DriverVersionSourceS3},
 	}
 	return cmd
 }
 
-func executeDrivers(_ *cobra.Command, _ []string) error {
+func executeDrivers(c *cobra.Command, _ []string) error {
 	cleaner, err := cleanup.NewS3Cleaner()
 	if err != nil {
 		return err
 	}
-	return cleanup.Run(cleanup.Options{Options: root.LoadRootOptions()}, cleaner)
+	options := cleanup.Options{Options: root.LoadRootOptions()}
+	err = cleanup.Run(c.Context(), options, cleaner)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
@@ -18,6 +18,8 @@ import (
 	"github.com/falcosecurity/dbg-go/cmd/build"
 	"github.com/falcosecurity/dbg-go/cmd/cleanup"
 	"github.com/falcosecurity/dbg-go/cmd/generate"
+	"github.com/falcosecurity/dbg-go/cmd/promote"
+	"github.com/falcosecurity/dbg-go/cmd/schema"
 	"github.com/falcosecurity/dbg-go/cmd/stats"
 	"github.com/falcosecurity/dbg-go/cmd/validate"
 	"github.com/spf13/cobra"
@@ -37,4 +39,6 @@ func init() {
 	configsCmd.AddCommand(validate.NewValidateConfigsCmd())
 	configsCmd.AddCommand(stats.NewStatsConfigsCmd())
 	configsCmd.AddCommand(build.NewBuildConfigsCmd())
+	configsCmd.AddCommand(promote.NewPromoteConfigsCmd())
+	configsCmd.AddCommand(schema.NewSchemaConfigsCmd())
 }
@@ -15,8 +15,12 @@ limitations under the License.
 package generate
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/falcosecurity/dbg-go/pkg/generate"
 	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -27,21 +31,81 @@ func NewGenerateConfigsCmd() *cobra.Command {
 		Short: "Generate new dbg configs",
 		Long: `In auto mode, configs will be generated starting from kernel-crawler output. 
 In this scenario, --target-{distro,kernelrelease,kernelversion} are available to filter to-be-generated configs. Regexes are allowed.
+--exclude-{distro,kernelrelease,kernelversion} can be used to skip some of the matched entries.
 Instead, when auto mode is disabled, the tool is able to generate a single config (for each driver version).
-In this scenario, --target-{distro,kernelrelease,kernelversion} CANNOT be regexes but must be exact values.
+In this scenario, --target-{distro,kernelrelease,kernelversion} CANNOT be regexes but must be exact, single, values.
 Also, in non-automatic mode, kernelurls will be retrieved using driverkit libraries.
+With a targets file, a config (for each driver version) is generated for each listed target, the same way.
+In host mode, the single config target, kernel release and kernel version are detected from the running host.
 `,
 		RunE: executeConfigs,
 	}
 	flags := cmd.Flags()
 	flags.Bool("auto", false, "automatically generate configs from kernel-crawler output")
+	flags.StringArray("crawler-input", nil, `in auto mode, kernel-crawler json url or local file ("-" for stdin) to be used instead of the public kernel-crawler output.
+Can be repeated to merge multiple sources: for configs listed by multiple sources, the last source wins.
+The "`+generate.CrawlerInputArchPlaceholder+`" placeholder, if present, is replaced by the architecture, eg: "crawler/`+generate.CrawlerInputArchPlaceholder+`/list.json".
+Public kernel-crawler output: "`+generate.DefaultCrawlerSource+`".`)
+	flags.String("crawler-cache-dir", generate.DefaultCrawlerCacheDir(), "in auto mode, directory where kernel-crawler downloads are cached, and only re-downloaded when changed. Empty disables caching.")
+	flags.Bool("crawler-refresh", false, "in auto mode, force a full kernel-crawler download, ignoring any cached copy.")
+	flags.Bool("sync", false, "in auto mode, also report stale configs, ie: configs matching target filters whose kernel is no longer listed by kernel-crawler.")
+	flags.Bool("prune", false, "in sync mode, remove stale configs.")
+	flags.Bool("host", false, "generate a single config for the running host, detecting its driverkit target, kernel release and kernel version.")
+	flags.String("host-os-release", generate.DefaultHostFiles.OSRelease, "in host mode, os-release file to detect the host distro from.")
+	flags.String("host-kernel-release", generate.DefaultHostFiles.KernelRelease, "in host mode, file holding the host kernel release, ie: `uname -r` output.")
+	flags.String("host-kernel-version", generate.DefaultHostFiles.KernelVersion, "in host mode, file holding the host kernel version, ie: `uname -v` output.")
+	flags.String("targets-file", "", `yaml or csv file listing explicit targets to generate configs for, resolving their kernel headers through driverkit, up to "jobs" in parallel.
+Yaml files hold a "targets" list of {distro,kernelrelease,kernelversion} objects; ".csv" files hold distro,kernelrelease[,kernelversion] records.`)
+	flags.StringArray("keep-latest", nil, `in auto mode, only generate configs for the latest N kernel releases of each target and major.minor kernel version.
+Either "N", for any target, or "<target>=N" (eg: "ubuntu-aws=5"); 0 means no limit. Can be repeated.
+In sync mode, configs of older kernel releases are reported as stale.
+Time based retention, eg: kernels published in the last N months, is not available: kernel-crawler entries carry no publication date.`)
+	flags.String("overlays", "", "overlays file, applied on top of generated configs; by default, driverkit/"+validate.OverlaysFileName+" is searched in the repo root.")
 	return cmd
 }
 
-func executeConfigs(_ *cobra.Command, _ []string) error {
+func executeConfigs(c *cobra.Command, _ []string) error {
+	overlays, err := validate.LoadOverlays(viper.GetString("overlays"), viper.GetString("repo-root"))
+	if err != nil {
+		return err
+	}
+	retention, err := generate.ParseRetention(viper.GetStringSlice("keep-latest"))
+	if err != nil {
+		return err
+	}
+	var targets []root.Target
+	if targetsFile := viper.GetString("targets-file"); targetsFile != "" {
+		targets, err = generate.LoadTargets(targetsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if !viper.GetBool("auto") {
+		// Outside auto mode, targets are literal values, used as they are: patterns cannot be joined
+		for _, key := range []string{"target-distro", "target-kernelrelease", "target-kernelversion"} {
+			if values := viper.GetStringSlice(key); len(values) > 1 {
+				return fmt.Errorf(`%s can only be repeated in "auto" mode, got: %s`, key, strings.Join(values, ","))
+			}
+		}
+	}
 	options := generate.Options{
-		Options: root.LoadRootOptions(),
-		Auto:    viper.GetBool("auto"),
+		Options:         root.LoadRootOptions(),
+		Auto:            viper.GetBool("auto"),
+		CrawlerInputs:   viper.GetStringSlice("crawler-input"),
+		CrawlerCacheDir: viper.GetString("crawler-cache-dir"),
+		CrawlerRefresh:  viper.GetBool("crawler-refresh"),
+		Sync:            viper.GetBool("sync"),
+		Prune:           viper.GetBool("prune"),
+		Overlays:        overlays,
+		Retention:       retention,
+		Host:            viper.GetBool("host"),
+		HostFiles: generate.HostFiles{
+			OSRelease:     viper.GetString("host-os-release"),
+			KernelRelease: viper.GetString("host-kernel-release"),
+			KernelVersion: viper.GetString("host-kernel-version"),
+		},
+		Targets: targets,
 	}
-	return generate.Run(options)
+	err = generate.Run(c.Context(), options)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
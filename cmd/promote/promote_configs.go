@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promote
+
+import (
+	"github.com/falcosecurity/dbg-go/pkg/promote"
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func NewPromoteConfigsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote dbg configs from a driver version to a new one",
+		Long: `Copy the configs of a driver version to a new one, recomputing their outputs for the new driver version.
+--target-{distro,kernelrelease,kernelversion}, --exclude-* and --kernel-range filters are honoured,
+to only promote a subset of the configs.
+`,
+		// Only --from and --to driver versions are used
+		Annotations: map[string]string{root.DriverVersionSourceAnnotation: root.DriverVersionSourceNone},
+		RunE:        executeConfigs,
+	}
+	flags := cmd.Flags()
+	flags.String("from", "", "driver version whose configs are promoted.")
+	flags.String("to", "", "driver version configs are promoted to.")
+	flags.String("overlays", "", "overlays file, applied on top of promoted configs; by default, driverkit/"+validate.OverlaysFileName+" is searched in the repo root.")
+	flags.Bool("drop-unbuildable", false, `skip configs for kernels supporting neither the kernel module nor the probe.
+This is a driver independent buildability check, only based on the kernel release: kernels that --to driver version no longer supports are not detected.`)
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func executeConfigs(c *cobra.Command, _ []string) error {
+	overlays, err := validate.LoadOverlays(viper.GetString("overlays"), viper.GetString("repo-root"))
+	if err != nil {
+		return err
+	}
+	options := promote.Options{
+		Options:         root.LoadRootOptions(),
+		From:            viper.GetString("from"),
+		To:              viper.GetString("to"),
+		DropUnbuildable: viper.GetBool("drop-unbuildable"),
+		Overlays:        overlays,
+	}
+	err = promote.Run(c.Context(), options)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
+}
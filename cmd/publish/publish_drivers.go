@@ -29,9 +29,10 @@ func NewPublishDriversCmd() *cobra.Command {
 	return cmd
 }
 
-func executeDrivers(_ *cobra.Command, _ []string) error {
+func executeDrivers(c *cobra.Command, _ []string) error {
 	options := publish.Options{
 		Options: root.LoadRootOptions(),
 	}
-	return publish.Run(options)
+	err := publish.Run(c.Context(), options)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
@@ -15,14 +15,18 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/falcosecurity/falcoctl/pkg/output"
 	"github.com/pterm/pterm"
 	"os"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/falcosecurity/dbg-go/pkg/root"
+	s3utils "github.com/falcosecurity/dbg-go/pkg/utils/s3"
 	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -39,42 +43,127 @@ var (
 			if err := viper.BindPFlags(cmd.Flags()); err != nil {
 				return err
 			}
+			// Configure the printer first, so that loading the config file honours the flags
+			if err := configurePrinter(); err != nil {
+				return err
+			}
+			if err := loadConfigFile(cmd); err != nil {
+				return err
+			}
+			// The config file might have changed the output
+			if err := configurePrinter(); err != nil {
+				return err
+			}
 
-			arch := viper.GetString("architecture")
-			driverVersions := viper.GetStringSlice("driver-version")
-			if _, present := kernelrelease.SupportedArchs[kernelrelease.Architecture(arch)]; !present {
-				return fmt.Errorf("arch %s is not supported", arch)
+			if jobs := viper.GetInt("jobs"); jobs < 1 {
+				return fmt.Errorf("jobs must be at least 1, got %d", jobs)
 			}
-			if len(driverVersions) == 0 {
-				if err := loadDriverVersions(); err != nil {
-					return err
-				}
+
+			if _, err := root.ParseArchitectures(viper.GetStringSlice("architecture")); err != nil {
+				return err
+			}
+			if err := validateTargetPatterns(); err != nil {
+				return err
+			}
+			if _, err := root.ParseKernelRange(viper.GetString("kernel-range")); err != nil {
+				return err
+			}
+			if err := loadDriverVersions(cmd); err != nil {
+				return err
 			}
-			root.Printer = output.NewPrinter(logLevel.ToPtermLogLevel(), pterm.LogFormatterColorful, os.Stdout)
 			return nil
 		},
 	}
 )
 
-func loadDriverVersions() error {
-	repoRoot := viper.GetString("repo-root")
-	configPath := repoRoot + "/driverkit/config/"
-	driverVersions := make([]string, 0)
-	entries, err := os.ReadDir(configPath)
+// configurePrinter makes logs honour the requested log level and output format.
+func configurePrinter() error {
+	outputFormat := root.OutputFormat(viper.GetString("output"))
+	if !slices.Contains(root.SupportedOutputFormats, string(outputFormat)) {
+		return fmt.Errorf("output format %s is not supported", outputFormat)
+	}
+	planToStdout := viper.GetBool("dry-run") && viper.GetString("plan-output") == root.PlanOutputStdout
+	if planToStdout && outputFormat.IsStructured() {
+		return fmt.Errorf("plan output to stdout cannot be used along with %s output: both would be written to stdout", outputFormat)
+	}
+	// Keep stdout clean when a machine-readable output, or plan, is requested
+	logWriter := os.Stdout
+	if outputFormat.IsStructured() || planToStdout {
+		logWriter = os.Stderr
+	}
+	root.Printer = output.NewPrinter(logLevel.ToPtermLogLevel(), pterm.LogFormatterColorful, logWriter)
+	return nil
+}
+
+func loadConfigFile(cmd *cobra.Command) error {
+	repoRoot, _ := cmd.Flags().GetString("repo-root")
+	configFile, err := root.FindConfigFile(viper.GetString("config"), repoRoot)
 	if err != nil {
 		return err
 	}
-	for _, e := range entries {
-		if e.IsDir() {
-			driverVersions = append(driverVersions, e.Name())
+	profile := viper.GetString("profile")
+	if configFile == "" {
+		if profile != "" {
+			return fmt.Errorf("profile %s requested but no %s config file found", profile, root.ConfigFileName)
 		}
+		return nil
 	}
+	// Sections are keyed by the command path, without the root command, eg: "configs: cleanup:"
+	commandPath := strings.Fields(cmd.CommandPath())[1:]
+	return root.LoadConfigFile(viper.GetViper(), configFile, profile, commandPath)
+}
 
-	if len(driverVersions) != 0 {
-		viper.Set("driver-version", driverVersions)
+// validateTargetPatterns makes sure that all target and exclusion filters are valid regexes.
+func validateTargetPatterns() error {
+	for _, key := range []string{
+		"target-distro", "target-kernelrelease", "target-kernelversion",
+		"exclude-distro", "exclude-kernelrelease", "exclude-kernelversion",
+	} {
+		for _, pattern := range viper.GetStringSlice(key) {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %w", key, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadDriverVersions resolves driver version selectors, or all the available driver versions
+// when none is requested, against the source the command works on.
+func loadDriverVersions(cmd *cobra.Command) error {
+	if cmd.Annotations[root.DriverVersionSourceAnnotation] == root.DriverVersionSourceNone {
 		return nil
 	}
-	return fmt.Errorf("no driver versions found")
+	requested := viper.GetStringSlice("driver-version")
+	if len(requested) > 0 && !slices.ContainsFunc(requested, root.IsDriverVersionSelector) {
+		// Only exact driver versions requested
+		return nil
+	}
+
+	var (
+		available []string
+		err       error
+	)
+	if cmd.Annotations[root.DriverVersionSourceAnnotation] == root.DriverVersionSourceS3 {
+		var client *s3utils.Client
+		client, err = s3utils.NewClient(true)
+		if err != nil {
+			return err
+		}
+		available, err = client.DriverVersions(cmd.Context())
+	} else {
+		available, err = root.LocalDriverVersions(viper.GetString("repo-root"))
+	}
+	if err != nil {
+		return err
+	}
+
+	driverVersions, err := root.ResolveDriverVersions(requested, available)
+	if err != nil {
+		return err
+	}
+	viper.Set("driver-version", driverVersions)
+	return nil
 }
 
 func init() {
@@ -84,26 +173,47 @@ func init() {
 	}
 
 	flags := rootCmd.PersistentFlags()
-	flags.Bool("dry-run", false, "enable dry-run mode.")
+	flags.String("config", "", "project config file; by default, "+root.ConfigFileName+" is searched in the repo root, then in the user config dir. Flags override its values.")
+	flags.String("profile", "", "named profile, from the project config file, to be applied.")
+	flags.Bool("dry-run", false, "enable dry-run mode; every entry that would be processed is listed, but nothing is touched.")
+	flags.String("plan-output", "", `when in dry-run mode, write the execution plan as a JSON report to the given file ("-" for stdout, logs going to stderr).`)
+	flags.IntP("jobs", "j", 1, "number of configs/drivers processed in parallel by local loops.")
 	flags.VarP(logLevel, "log-level", "l", "set log verbosity "+logLevel.Allowed())
+	flags.StringP("output", "o", string(root.OutputText), "output format of the command result. Supported: ["+strings.Join(root.SupportedOutputFormats, ",")+"].")
 	flags.String("repo-root", cwd, "test-infra repository root path.")
-	flags.StringP("architecture", "a", runtime.GOARCH, `architecture to run against. Supported: `+kernelrelease.SupportedArchs.String())
-	flags.StringSlice("driver-version", nil, "driver versions to run against.")
+	flags.StringSliceP("architecture", "a", []string{runtime.GOARCH}, `architectures to run against; "`+root.AllArchitectures+`" means every supported one. Supported: `+kernelrelease.SupportedArchs.String())
+	flags.StringSlice("driver-version", nil, `driver versions to run against. Besides exact versions, semver constraints (eg: ">=6.0.0") and "`+root.LatestDriverVersion+`[:N]" are allowed.
+By default, all the available driver versions are used.`)
 	flags.String("driver-name", "falco", "driver name to be used")
-	flags.String("target-kernelrelease", "",
-		`target kernel release to work against. By default tool will work on any kernel release. Can be a regex.`)
-	flags.String("target-kernelversion", "",
-		`target kernel version to work against. By default tool will work on any kernel version. Can be a regex.`)
-	flags.String("target-distro", "",
-		`target distro to work against. By default tool will work on any supported distro. Can be a regex.
+	flags.StringArray("target-kernelrelease", nil,
+		`target kernel release to work against. By default tool will work on any kernel release. Can be a regex; can be repeated to match any of the patterns.`)
+	flags.StringArray("target-kernelversion", nil,
+		`target kernel version to work against. By default tool will work on any kernel version. Can be a regex; can be repeated to match any of the patterns.`)
+	flags.StringArray("target-distro", nil,
+		`target distro to work against. By default tool will work on any supported distro. Can be a regex; can be repeated to match any of the patterns.
 Supported: [`+strings.Join(root.SupportedDistroSlice, ",")+"].")
+	flags.String("kernel-range", "",
+		`semantic kernel release range to work against, eg: ">=5.8 <6.2". Constraints are space or comma separated, and all of them must be satisfied.
+Supported operators: [>=,>,<=,<,=,!=].`)
+	flags.StringArray("exclude-kernelrelease", nil,
+		`kernel release to be skipped, even if matched by target-kernelrelease. Can be a regex; can be repeated.`)
+	flags.StringArray("exclude-kernelversion", nil,
+		`kernel version to be skipped, even if matched by target-kernelversion. Can be a regex; can be repeated.`)
+	flags.StringArray("exclude-distro", nil,
+		`distro to be skipped, even if matched by target-distro. Can be a regex; can be repeated.`)
 
 	// Custom completions
 	_ = rootCmd.RegisterFlagCompletionFunc("target-distro", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return root.SupportedDistroSlice, cobra.ShellCompDirectiveDefault
 	})
+	_ = rootCmd.RegisterFlagCompletionFunc("exclude-distro", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return root.SupportedDistroSlice, cobra.ShellCompDirectiveDefault
+	})
+	_ = rootCmd.RegisterFlagCompletionFunc("output", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return root.SupportedOutputFormats, cobra.ShellCompDirectiveDefault
+	})
 	_ = rootCmd.RegisterFlagCompletionFunc("architecture", func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return kernelrelease.SupportedArchs.Strings(), cobra.ShellCompDirectiveDefault
+		return append(kernelrelease.SupportedArchs.Strings(), root.AllArchitectures), cobra.ShellCompDirectiveDefault
 	})
 
 	// Subcommands
@@ -111,6 +221,6 @@ Supported: [`+strings.Join(root.SupportedDistroSlice, ",")+"].")
 	rootCmd.AddCommand(s3Cmd)
 }
 
-func Execute() error {
-	return rootCmd.Execute()
+func Execute(ctx context.Context) error {
+	return rootCmd.ExecuteContext(ctx)
 }
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/spf13/cobra"
+)
+
+func NewSchemaConfigsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of dbg configs",
+		Long: `Print the JSON Schema of driverkit configs, generated from the config type, eg: for editor integration.
+The schema matches the checks run by "configs validate --strict".
+`,
+		Annotations: map[string]string{
+			root.DriverVersionSourceAnnotation: root.DriverVersionSourceNone,
+		},
+		Args: cobra.NoArgs,
+		RunE: executeConfigs,
+	}
+	return cmd
+}
+
+func executeConfigs(c *cobra.Command, _ []string) error {
+	schema, err := validate.Schema()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.OutOrStdout(), string(schema))
+	return err
+}
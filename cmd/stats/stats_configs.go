@@ -29,6 +29,8 @@ func NewStatsConfigsCmd() *cobra.Command {
 	return cmd
 }
 
-func executeConfigs(_ *cobra.Command, _ []string) error {
-	return stats.Run(stats.Options{Options: root.LoadRootOptions()}, stats.NewFileStatter())
+func executeConfigs(c *cobra.Command, _ []string) error {
+	options := stats.Options{Options: root.LoadRootOptions()}
+	err := stats.Run(c.Context(), options, stats.NewFileStatter())
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
@@ -25,14 +25,18 @@ func NewStatsDriversCmd() *cobra.Command {
 		Use:   "stats",
 		Short: "Fetch stats about remote drivers",
 		RunE:  executeDrivers,
+		// Driver version selectors are resolved against the bucket
+		Annotations: map[string]string{root.DriverVersionSourceAnnotation: root.DriverVersionSourceS3},
 	}
 	return cmd
 }
 
-func executeDrivers(_ *cobra.Command, _ []string) error {
+func executeDrivers(c *cobra.Command, _ []string) error {
 	statter, err := stats.NewS3Statter()
 	if err != nil {
 		return err
 	}
-	return stats.Run(stats.Options{Options: root.LoadRootOptions()}, statter)
+	options := stats.Options{Options: root.LoadRootOptions()}
+	err = stats.Run(c.Context(), options, statter)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
@@ -15,9 +15,12 @@ limitations under the License.
 package validate
 
 import (
+	"strings"
+
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	"github.com/falcosecurity/dbg-go/pkg/validate"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func NewValidateConfigsCmd() *cobra.Command {
@@ -26,12 +29,42 @@ func NewValidateConfigsCmd() *cobra.Command {
 		Short: "Validate dbg configs",
 		RunE:  executeConfigs,
 	}
+	flags := cmd.Flags()
+	flags.Bool("keep-going", false, "validate all the configs, reporting every problem found, instead of stopping at the first invalid config.")
+	flags.Bool("fix", false, `rewrite or rename configs with mechanical errors, ie: wrong filename, architecture or output paths, printing a diff;
+fixed configs are validated again. In dry-run mode, fixes are only printed.`)
+	flags.Bool("strict", false, "reject configs with unknown fields, or missing fields required by their target.")
+	flags.Bool("consistency", false, `once all the configs are validated, run cross-config checks, eg: the same kernel under multiple distros,
+or configs missing for the next driver version; their findings are only reported as warnings.`)
+	flags.Bool("check-urls", false, `check that kernelurls are reachable, with a HEAD request for each unique url,
+and that enough of them are left for the target builder.`)
+	flags.Float64("url-rate", validate.DefaultURLRate, "maximum number of HEAD requests per second issued by --check-urls.")
+	flags.StringArray("report", nil, `validation report to be written, as "<format>=<path>"; can be repeated.
+Supported formats: [`+strings.Join(validate.SupportedReportFormats, ",")+`].`)
+	flags.String("overlays", "", "overlays file, that configs are checked against for drift; by default, driverkit/"+validate.OverlaysFileName+" is searched in the repo root.")
 	return cmd
 }
 
-func executeConfigs(c *cobra.Command, args []string) error {
+func executeConfigs(c *cobra.Command, _ []string) error {
+	overlays, err := validate.LoadOverlays(viper.GetString("overlays"), viper.GetString("repo-root"))
+	if err != nil {
+		return err
+	}
+	reports, err := validate.ParseReports(viper.GetStringSlice("report"))
+	if err != nil {
+		return err
+	}
 	options := validate.Options{
-		Options: root.LoadRootOptions(),
+		Options:     root.LoadRootOptions(),
+		Overlays:    overlays,
+		KeepGoing:   viper.GetBool("keep-going"),
+		Reports:     reports,
+		Fix:         viper.GetBool("fix"),
+		Strict:      viper.GetBool("strict"),
+		Consistency: viper.GetBool("consistency"),
+		CheckURLs:   viper.GetBool("check-urls"),
+		URLRate:     viper.GetFloat64("url-rate"),
 	}
-	return validate.Run(options)
+	err = validate.Run(c.Context(), options)
+	return root.WriteResult(c.CommandPath(), options.Options, err)
 }
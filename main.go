@@ -15,12 +15,26 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/falcosecurity/dbg-go/cmd"
 	"github.com/falcosecurity/dbg-go/pkg/root"
 )
 
 func main() {
-	if err := cmd.Execute(); err != nil {
+	// First signal gracefully stops the run: no new work is started,
+	// in-flight work is completed or cancelled, and a result is reported anyway.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		// Restore default behavior: a second signal kills the process.
+		stop()
+	}()
+
+	if err := cmd.Execute(ctx); err != nil {
 		root.Printer.Logger.Error("error executing dbg-go", root.Printer.Logger.Args("err", err))
 	}
 }
@@ -15,6 +15,7 @@ limitations under the License.
 package build
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,6 +26,7 @@ import (
 	"github.com/falcosecurity/dbg-go/pkg/validate"
 	"github.com/falcosecurity/driverkit/cmd"
 	"github.com/falcosecurity/driverkit/pkg/driverbuilder"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -32,7 +34,7 @@ import (
 // Used by tests
 var testClient *s3utils.Client
 
-func Run(opts Options) error {
+func Run(ctx context.Context, opts Options) error {
 	root.Printer.Logger.Info("building drivers")
 	var (
 		client *s3utils.Client
@@ -65,12 +67,16 @@ func Run(opts Options) error {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			publishLoop(publishCh, opts.Options, client)
+			// Drain the queue even when the run gets interrupted,
+			// so that already built drivers are not lost.
+			publishLoop(context.WithoutCancel(ctx), publishCh, opts.Options, client)
 		}()
 	}
 
-	err = looper.LoopFiltered(opts.Options, "building driver", "config", func(driverVersion, configPath string) error {
-		return buildConfig(client, opts, publishCh, redirectErrorsF, driverVersion, configPath)
+	err = looper.LoopFiltered(ctx, opts.Options, "building driver", "config", func(ctx context.Context, arch kernelrelease.Architecture, driverVersion, configPath string) error {
+		archOpts := opts
+		archOpts.Options = opts.ForArchitecture(arch)
+		return buildConfig(ctx, client, archOpts, publishCh, redirectErrorsF, driverVersion, configPath)
 	})
 
 	if publishCh != nil {
@@ -80,7 +86,7 @@ func Run(opts Options) error {
 	return err
 }
 
-func buildConfig(client *s3utils.Client, opts Options,
+func buildConfig(ctx context.Context, client *s3utils.Client, opts Options,
 	publishCh chan<- publishVal, redirectErrorsF *os.File,
 	driverVersion, configPath string) error {
 
@@ -125,28 +131,37 @@ func buildConfig(client *s3utils.Client, opts Options,
 	if opts.SkipExisting {
 		if ro.Output.Module != "" {
 			moduleName := filepath.Base(ro.Output.Module)
-			if client.HeadDriver(opts.Options, driverVersion, moduleName) {
+			if client.HeadDriver(ctx, opts.Options, driverVersion, moduleName) {
 				root.Printer.Logger.Info("output module already exists inside S3 bucket - skipping", args)
 				ro.Output.Module = "" // disable module build
 			}
 		}
 		if ro.Output.Probe != "" {
 			probeName := filepath.Base(ro.Output.Probe)
-			if client.HeadDriver(opts.Options, driverVersion, probeName) {
+			if client.HeadDriver(ctx, opts.Options, driverVersion, probeName) {
 				root.Printer.Logger.Info("output probe already exists inside S3 bucket - skipping", args)
 				ro.Output.Probe = "" // disable probe build
 			}
 		}
 		if ro.Output.Module == "" && ro.Output.Probe == "" {
 			root.Printer.Logger.Info("drivers already available on S3 bucket, skipping build", args)
-			return nil // nothing to do
+			return fmt.Errorf("%w: drivers already available on S3 bucket", root.ErrSkipped) // nothing to do
 		}
 	}
 
 	// Ensure output folder exist; don't check for error, it will fail at next step anyway.
 	_ = os.MkdirAll(filepath.Dir(driverkitYaml.Output.Module), 0700)
 
+	// Do not start a new build once interrupted
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	// Driverkit does not take a context, but it listens for the same signals on its own,
+	// stopping and removing the build container.
 	err = driverbuilder.NewDockerBuildProcessor(1000, "").Start(ro.ToBuild(root.Printer))
+	if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
 	if err != nil {
 		if redirectErrorsF != nil {
 			logLine := fmt.Sprintf("config: %s | error: %s\n", configPath, err.Error())
@@ -154,13 +169,14 @@ func buildConfig(client *s3utils.Client, opts Options,
 		}
 		if opts.IgnoreErrors {
 			root.Printer.Logger.Error(err.Error(), args)
-			return nil // do not break the configs loop, just try the next one
+			return &root.IgnoredErr{Err: err} // do not break the configs loop, just try the next one
 		}
 		return err
 	}
 
 	if publishCh != nil {
 		publishCh <- publishVal{
+			arch:          opts.Architecture,
 			driverVersion: driverVersion,
 			out:           ro.Output,
 		}
@@ -168,30 +184,32 @@ func buildConfig(client *s3utils.Client, opts Options,
 	return nil
 }
 
-func publishLoop(publishCh <-chan publishVal, opts root.Options, client *s3utils.Client) {
+func publishLoop(ctx context.Context, publishCh <-chan publishVal, opts root.Options, client *s3utils.Client) {
 	for val := range publishCh {
-		if val.out.Module != "" {
-			err := client.PutDriver(opts, val.driverVersion, val.out.Module)
-			if err != nil {
-				root.Printer.Logger.Warn("failed to upload module",
-					root.Printer.Logger.Args(
-						"path", val.out.Module,
-						"err", err.Error()))
-			} else {
-				root.Printer.Logger.Info("published module",
-					root.Printer.Logger.Args("path", val.out.Module))
+		archOpts := opts.ForArchitecture(val.arch)
+		for _, driver := range []struct{ kind, path string }{
+			{"module", val.out.Module},
+			{"probe", val.out.Probe},
+		} {
+			kind, path := driver.kind, driver.path
+			if path == "" {
+				continue
 			}
-		}
-		if val.out.Probe != "" {
-			err := client.PutDriver(opts, val.driverVersion, val.out.Probe)
+			err := client.PutDriver(ctx, archOpts, val.driverVersion, path)
+			opts.Result.Record(root.ResultItem{
+				Action:        "publishing",
+				Architecture:  val.arch,
+				DriverVersion: val.driverVersion,
+				Path:          path,
+			}, err)
 			if err != nil {
-				root.Printer.Logger.Warn("failed to upload probe",
+				root.Printer.Logger.Warn("failed to upload "+kind,
 					root.Printer.Logger.Args(
-						"path", val.out.Probe,
+						"path", path,
 						"err", err.Error()))
 			} else {
-				root.Printer.Logger.Info("published probe",
-					root.Printer.Logger.Args("path", val.out.Probe))
+				root.Printer.Logger.Info("published "+kind,
+					root.Printer.Logger.Args("path", path))
 			}
 		}
 	}
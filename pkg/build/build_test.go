@@ -189,14 +189,14 @@ func TestBuild(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			now := time.Now()
 			// First, generate needed configs
-			err = generate.Run(generate.Options{
+			err = generate.Run(context.Background(), generate.Options{
 				Options: test.opts.Options,
 				Auto:    false,
 			})
 			assert.NoError(t, err)
 
 			// Build the configs!
-			err = Run(test.opts)
+			err = Run(context.Background(), test.opts)
 			assert.NoError(t, err)
 
 			// Check that the files were created
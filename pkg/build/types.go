@@ -17,6 +17,7 @@ package build
 import (
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	"github.com/falcosecurity/driverkit/cmd"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 type Options struct {
@@ -28,6 +29,7 @@ type Options struct {
 }
 
 type publishVal struct {
+	arch          kernelrelease.Architecture
 	driverVersion string
 	out           cmd.OutputOptions
 }
@@ -15,9 +15,11 @@ limitations under the License.
 package cleanup
 
 import (
+	"context"
 	"os"
 
 	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 type fileCleaner struct {
@@ -32,8 +34,8 @@ func (f *fileCleaner) Info() string {
 	return "cleaning up local config files"
 }
 
-func (f *fileCleaner) Cleanup(opts Options) error {
-	return f.LoopFiltered(opts.Options, "removing file", "config", func(driverVersion, configPath string) error {
+func (f *fileCleaner) Cleanup(ctx context.Context, opts Options) error {
+	return f.LoopFiltered(ctx, opts.Options, "removing file", "config", func(_ context.Context, _ kernelrelease.Architecture, _, configPath string) error {
 		return os.Remove(configPath)
 	})
 }
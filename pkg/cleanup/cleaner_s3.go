@@ -20,6 +20,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3utils "github.com/falcosecurity/dbg-go/pkg/utils/s3"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 type s3Cleaner struct {
@@ -38,9 +39,9 @@ func (s *s3Cleaner) Info() string {
 	return "cleaning up remote driver files"
 }
 
-func (s *s3Cleaner) Cleanup(opts Options) error {
-	return s.LoopFiltered(opts.Options, "cleaning up remote driver file", "key", func(driverVersion, key string) error {
-		_, err := s.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+func (s *s3Cleaner) Cleanup(ctx context.Context, opts Options) error {
+	return s.LoopFiltered(ctx, opts.Options, "cleaning up remote driver file", "key", func(ctx context.Context, _ kernelrelease.Architecture, _, key string) error {
+		_, err := s.DeleteObject(ctx, &s3.DeleteObjectInput{
 			Bucket: aws.String(s3utils.S3Bucket),
 			Key:    aws.String(key),
 		})
@@ -15,10 +15,12 @@ limitations under the License.
 package cleanup
 
 import (
+	"context"
+
 	"github.com/falcosecurity/dbg-go/pkg/root"
 )
 
-func Run(opts Options, cleaner Cleaner) error {
+func Run(ctx context.Context, opts Options, cleaner Cleaner) error {
 	root.Printer.Logger.Info(cleaner.Info())
-	return cleaner.Cleanup(opts)
+	return cleaner.Cleanup(ctx, opts)
 }
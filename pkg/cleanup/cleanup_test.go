@@ -72,7 +72,7 @@ func TestCleanup(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			err = Run(test.opts, NewFileCleaner())
+			err = Run(context.Background(), test.opts, NewFileCleaner())
 			if test.errorExpected {
 				assert.Error(t, err)
 			} else {
@@ -175,7 +175,7 @@ func TestCleanupFiltered(t *testing.T) {
 			found := 0
 			lines := 0
 			testutils.RunTestParsingLogs(t, func() error {
-				return Run(test.opts, NewFileCleaner())
+				return Run(context.Background(), test.opts, NewFileCleaner())
 			}, func(line []byte) bool {
 				err = json.Unmarshal(line, &messageJSON)
 				assert.NoError(t, err)
@@ -299,7 +299,7 @@ func TestCleanupS3(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := Run(test.opts, cleaner)
+			err := Run(context.Background(), test.opts, cleaner)
 			assert.NoError(t, err)
 
 			// Check the remaining objects in the bucket
@@ -14,7 +14,11 @@ limitations under the License.
 
 package cleanup
 
-import "github.com/falcosecurity/dbg-go/pkg/root"
+import (
+	"context"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+)
 
 type Options struct {
 	root.Options
@@ -22,5 +26,5 @@ type Options struct {
 
 type Cleaner interface {
 	Info() string
-	Cleanup(opts Options) error
+	Cleanup(ctx context.Context, opts Options) error
 }
@@ -15,5 +15,17 @@ limitations under the License.
 package generate
 
 const (
-	urlArchFmt = "https://falcosecurity.github.io/kernel-crawler/%s/list.json"
+	// DefaultCrawlerSource is the public kernel-crawler output, used when no crawler input is passed.
+	DefaultCrawlerSource = "https://falcosecurity.github.io/kernel-crawler/" + CrawlerInputArchPlaceholder + "/list.json"
+
+	// CrawlerInputStdin makes the crawler input to be read from stdin.
+	CrawlerInputStdin = "-"
+	// CrawlerInputArchPlaceholder is replaced with the non-deb architecture (eg: x86_64) in the crawler input path or url.
+	CrawlerInputArchPlaceholder = "{arch}"
+
+	// crawlerDecodeBufferSize is the size of the buffer kernel-crawler jsons are streamed through.
+	crawlerDecodeBufferSize = 64 * 1024
+
+	actionGenerating = "generating"
+	actionRemoving   = "removing"
 )
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	json "github.com/json-iterator/go"
+)
+
+const (
+	crawlerFetchAttempts = 3
+	crawlerFetchTimeout  = 10 * time.Minute
+)
+
+var (
+	crawlerHTTPClient = &http.Client{Timeout: crawlerFetchTimeout}
+	// crawlerRetryBackoff is multiplied by the attempt number between retries; tests override it.
+	crawlerRetryBackoff = 2 * time.Second
+)
+
+// DefaultCrawlerCacheDir returns the default kernel-crawler downloads cache dir,
+// ie: $XDG_CACHE_HOME/dbg-go/kernel-crawler, or an empty string if there is no user cache dir.
+func DefaultCrawlerCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cacheDir, "dbg-go", "kernel-crawler")
+}
+
+type crawlerCacheMetadata struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// crawlerCache stores kernel-crawler downloads, keyed by architecture and source url,
+// together with the metadata needed to issue conditional requests.
+type crawlerCache struct {
+	dir string
+}
+
+func (c crawlerCache) paths(arch kernelrelease.Architecture, url string) (string, string) {
+	sum := sha256.Sum256([]byte(url))
+	base := filepath.Join(c.dir, arch.ToNonDeb(), hex.EncodeToString(sum[:8]))
+	return base + ".json", base + ".meta.json"
+}
+
+// lookup returns the path of the cached data, if any, for the url.
+func (c crawlerCache) lookup(arch kernelrelease.Architecture, url string) (string, crawlerCacheMetadata, bool) {
+	var meta crawlerCacheMetadata
+	dataPath, metaPath := c.paths(arch, url)
+	metaData, err := os.ReadFile(metaPath)
+	if err != nil || json.Unmarshal(metaData, &meta) != nil || meta.URL != url {
+		return "", meta, false
+	}
+	if _, err = os.Stat(dataPath); err != nil {
+		return "", meta, false
+	}
+	return dataPath, meta, true
+}
+
+// create returns a temporary file, in the cache dir, where a download can be stored before being committed.
+func (c crawlerCache) create(arch kernelrelease.Architecture, url string) (*os.File, error) {
+	dataPath, _ := c.paths(arch, url)
+	if err := os.MkdirAll(filepath.Dir(dataPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(filepath.Dir(dataPath), "."+filepath.Base(dataPath)+".*")
+}
+
+// commit moves a completed download, stored in tmpPath, into the cache.
+func (c crawlerCache) commit(arch kernelrelease.Architecture, meta crawlerCacheMetadata, tmpPath string) error {
+	dataPath, metaPath := c.paths(arch, meta.URL)
+	metaData, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpPath, 0o644); err != nil {
+		return err
+	}
+	// Data first: a stale metadata file at worst triggers a full download
+	if err = os.Rename(tmpPath, dataPath); err != nil {
+		return err
+	}
+	return root.WriteFileAtomic(metaPath, metaData, 0o644)
+}
+
+// cachingReader streams a download, while storing it into a temporary cache file,
+// that is only committed to the cache once the download was fully read, and successfully decoded.
+// Failing to cache the download is never fatal.
+type cachingReader struct {
+	body    io.ReadCloser
+	tmp     *os.File
+	tmpErr  error
+	cache   crawlerCache
+	arch    kernelrelease.Architecture
+	meta    crawlerCacheMetadata
+	readErr error
+	decoded bool
+}
+
+// markDecoded tells a caching download that its data was successfully decoded,
+// so that it is committed to the cache on Close; it is a no-op on any other reader.
+func markDecoded(r io.Reader) {
+	if c, ok := r.(*cachingReader); ok {
+		c.decoded = true
+	}
+}
+
+func (c *cachingReader) Read(p []byte) (int, error) {
+	n, err := c.body.Read(p)
+	if n > 0 && c.tmpErr == nil {
+		_, c.tmpErr = c.tmp.Write(p[:n])
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.readErr = err
+	}
+	return n, err
+}
+
+// Close drains what is left of a decoded download, eg: trailing whitespaces, so that it can be committed to the cache.
+// Downloads that failed to be read or decoded, eg: truncated or garbage ones, are discarded.
+func (c *cachingReader) Close() error {
+	commit := c.decoded && c.readErr == nil
+	if commit {
+		_, _ = io.Copy(io.Discard, c)
+		commit = c.readErr == nil
+	}
+	err := c.body.Close()
+	tmpErr := c.tmp.Close()
+	if c.tmpErr == nil {
+		c.tmpErr = tmpErr
+	}
+	if commit && c.tmpErr == nil {
+		c.tmpErr = c.cache.commit(c.arch, c.meta, c.tmp.Name())
+	}
+	if commit && c.tmpErr != nil {
+		root.Printer.Logger.Warn("failed to cache json data",
+			root.Printer.Logger.Args("url", c.meta.URL, "err", c.tmpErr))
+	}
+	// No-op once committed
+	_ = os.Remove(c.tmp.Name())
+	return err
+}
+
+// fetchCrawlerData streams the kernel-crawler json at url, using the cache in opts.CrawlerCacheDir, if any.
+// A cached copy is only re-downloaded when the server reports that it changed, unless opts.CrawlerRefresh is set.
+// The caller must close the returned reader.
+func fetchCrawlerData(ctx context.Context, opts Options, url string) (io.ReadCloser, error) {
+	cache := crawlerCache{dir: opts.CrawlerCacheDir}
+	var (
+		cachedPath string
+		meta       = crawlerCacheMetadata{URL: url}
+		cached     bool
+	)
+	if cache.dir != "" && !opts.CrawlerRefresh {
+		cachedPath, meta, cached = cache.lookup(opts.Architecture, url)
+		if !cached {
+			meta = crawlerCacheMetadata{URL: url}
+		}
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 1; attempt <= crawlerFetchAttempts; attempt++ {
+		resp, err = doCrawlerRequest(ctx, url, meta, cached)
+		if err == nil || ctx.Err() != nil || attempt == crawlerFetchAttempts {
+			break
+		}
+		var statusErr *UnexpectedStatusErr
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			break
+		}
+		root.Printer.Logger.Warn("failed to download json data, retrying",
+			root.Printer.Logger.Args("url", url, "attempt", attempt, "err", err))
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Duration(attempt) * crawlerRetryBackoff):
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close()
+		root.Printer.Logger.Debug("using cached json data",
+			root.Printer.Logger.Args("url", url))
+		return os.Open(cachedPath)
+	}
+	if cache.dir == "" {
+		return resp.Body, nil
+	}
+	tmp, err := cache.create(opts.Architecture, url)
+	if err != nil {
+		// Not fatal: data can be downloaded anyway
+		root.Printer.Logger.Warn("failed to cache json data",
+			root.Printer.Logger.Args("url", url, "err", err))
+		return resp.Body, nil
+	}
+	meta.ETag = resp.Header.Get("ETag")
+	meta.LastModified = resp.Header.Get("Last-Modified")
+	return &cachingReader{
+		body:  resp.Body,
+		tmp:   tmp,
+		cache: cache,
+		arch:  opts.Architecture,
+		meta:  meta,
+	}, nil
+}
+
+// doCrawlerRequest issues a single, conditional when there is a cached copy, request.
+// On success, the returned response status is either 200 or 304.
+func doCrawlerRequest(ctx context.Context, url string, meta crawlerCacheMetadata, cached bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	resp, err := crawlerHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if resp.StatusCode == http.StatusOK || (cached && resp.StatusCode == http.StatusNotModified) {
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	return nil, &UnexpectedStatusErr{url: url, statusCode: resp.StatusCode}
+}
@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCrawlerData = `{"centos":[{"kernelversion":"1","kernelrelease":"5.14.0-325.el9.x86_64","target":"centos"}]}`
+
+// newCrawlerServer returns a server that serves testCrawlerData with the given ETag,
+// honoring conditional requests, after failing the first `failures` requests with `failureStatus`.
+func newCrawlerServer(t *testing.T, etag string, failures int32, failureStatus int) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	var requests, downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(failureStatus)
+			return
+		}
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		downloads.Add(1)
+		_, _ = w.Write([]byte(testCrawlerData))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests, &downloads
+}
+
+func TestFetchCrawlerData(t *testing.T) {
+	tests := map[string]struct {
+		etag              string
+		failures          int32
+		failureStatus     int
+		cache             bool
+		refresh           bool
+		fetches           int
+		expectError       bool
+		expectedRequests  int32
+		expectedDownloads int32
+	}{
+		"no cache always downloads": {
+			etag:              `"v1"`,
+			fetches:           2,
+			expectedRequests:  2,
+			expectedDownloads: 2,
+		},
+		"cache is revalidated": {
+			etag:              `"v1"`,
+			cache:             true,
+			fetches:           3,
+			expectedRequests:  3,
+			expectedDownloads: 1,
+		},
+		"cache without etag downloads": {
+			cache:             true,
+			fetches:           2,
+			expectedRequests:  2,
+			expectedDownloads: 2,
+		},
+		"refresh ignores cache": {
+			etag:              `"v1"`,
+			cache:             true,
+			refresh:           true,
+			fetches:           2,
+			expectedRequests:  2,
+			expectedDownloads: 2,
+		},
+		"transient failures are retried": {
+			failures:          2,
+			failureStatus:     http.StatusServiceUnavailable,
+			fetches:           1,
+			expectedRequests:  3,
+			expectedDownloads: 1,
+		},
+		"too many transient failures": {
+			failures:         crawlerFetchAttempts,
+			failureStatus:    http.StatusBadGateway,
+			fetches:          1,
+			expectError:      true,
+			expectedRequests: crawlerFetchAttempts,
+		},
+		"not found is not retried": {
+			failures:         1,
+			failureStatus:    http.StatusNotFound,
+			fetches:          1,
+			expectError:      true,
+			expectedRequests: 1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			srv, requests, downloads := newCrawlerServer(t, test.etag, test.failures, test.failureStatus)
+			opts := Options{
+				Options:        root.Options{Architecture: "amd64"},
+				CrawlerRefresh: test.refresh,
+			}
+			if test.cache {
+				opts.CrawlerCacheDir = t.TempDir()
+			}
+			for i := 0; i < test.fetches; i++ {
+				r, err := fetchCrawlerData(context.Background(), opts, srv.URL+"/x86_64/list.json")
+				if test.expectError {
+					assert.Error(t, err)
+					continue
+				}
+				assert.NoError(t, err)
+				data, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				markDecoded(r)
+				assert.NoError(t, r.Close())
+				assert.Equal(t, testCrawlerData, string(data))
+			}
+			assert.Equal(t, test.expectedRequests, requests.Load())
+			assert.Equal(t, test.expectedDownloads, downloads.Load())
+		})
+	}
+}
+
+func TestFetchCrawlerDataCacheKey(t *testing.T) {
+	srv, _, downloads := newCrawlerServer(t, `"v1"`, 0, 0)
+	opts := Options{
+		Options:         root.Options{Architecture: "amd64"},
+		CrawlerCacheDir: t.TempDir(),
+	}
+
+	// Each url, and each architecture, has its own cache entry
+	fetch := func(url string) {
+		r, err := fetchCrawlerData(context.Background(), opts, url)
+		assert.NoError(t, err)
+		_, err = io.Copy(io.Discard, r)
+		assert.NoError(t, err)
+		markDecoded(r)
+		assert.NoError(t, r.Close())
+	}
+	fetch(srv.URL + "/x86_64/list.json")
+	fetch(srv.URL + "/other/list.json")
+	opts.Architecture = "arm64"
+	fetch(srv.URL + "/x86_64/list.json")
+	assert.Equal(t, int32(3), downloads.Load())
+
+	opts.Architecture = "amd64"
+	fetch(srv.URL + "/x86_64/list.json")
+	assert.Equal(t, int32(3), downloads.Load())
+}
+
+func TestFetchCrawlerDataCancel(t *testing.T) {
+	srv, requests, _ := newCrawlerServer(t, "", crawlerFetchAttempts, http.StatusServiceUnavailable)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := fetchCrawlerData(ctx, Options{Options: root.Options{Architecture: "amd64"}}, srv.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), requests.Load())
+}
+
+func TestFetchCrawlerDataPartialRead(t *testing.T) {
+	srv, requests, downloads := newCrawlerServer(t, `"v1"`, 0, 0)
+	opts := Options{
+		Options:         root.Options{Architecture: "amd64"},
+		CrawlerCacheDir: t.TempDir(),
+	}
+
+	// Whatever is left unread by the decoder is drained on close, and the download gets cached anyway
+	r, err := fetchCrawlerData(context.Background(), opts, srv.URL+"/x86_64/list.json")
+	assert.NoError(t, err)
+	_, err = r.Read(make([]byte, 1))
+	assert.NoError(t, err)
+	markDecoded(r)
+	assert.NoError(t, r.Close())
+
+	r, err = fetchCrawlerData(context.Background(), opts, srv.URL+"/x86_64/list.json")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.Equal(t, testCrawlerData, string(data))
+	assert.Equal(t, int32(2), requests.Load())
+	assert.Equal(t, int32(1), downloads.Load())
+}
+
+func TestFetchCrawlerDataNotDecoded(t *testing.T) {
+	srv, _, downloads := newCrawlerServer(t, `"v1"`, 0, 0)
+	opts := Options{
+		Options:         root.Options{Architecture: "amd64"},
+		CrawlerCacheDir: t.TempDir(),
+	}
+
+	// Downloads that were not successfully decoded are never cached
+	for i := 0; i < 2; i++ {
+		r, err := fetchCrawlerData(context.Background(), opts, srv.URL+"/x86_64/list.json")
+		assert.NoError(t, err)
+		_, err = io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.NoError(t, r.Close())
+	}
+	assert.Equal(t, int32(2), downloads.Load())
+	files, err := filepath.Glob(filepath.Join(opts.CrawlerCacheDir, "*", "*"))
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestWalkCrawlerEntriesGarbageNotCached(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		// Truncated json
+		_, _ = w.Write([]byte(testCrawlerData[:len(testCrawlerData)/2]))
+	}))
+	t.Cleanup(srv.Close)
+	opts := Options{
+		Options:         root.Options{Architecture: "amd64"},
+		Auto:            true,
+		CrawlerInputs:   []string{srv.URL + "/{arch}/list.json"},
+		CrawlerCacheDir: t.TempDir(),
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := walkCrawlerEntries(context.Background(), opts, func(string, []crawlerEntry) error {
+			return nil
+		})
+		assert.Error(t, err)
+	}
+	// The truncated download was never served from the cache
+	assert.Equal(t, int32(2), downloads.Load())
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	json "github.com/json-iterator/go"
+)
+
+// crawlerEntry is a kernel-crawler entry, together with the source it was loaded from.
+type crawlerEntry struct {
+	validate.DriverkitYaml
+	source string
+}
+
+// crawlerSources returns the kernel-crawler sources to be loaded, in precedence order (last wins).
+func (o Options) crawlerSources() []string {
+	if len(o.CrawlerInputs) == 0 {
+		return []string{DefaultCrawlerSource}
+	}
+	return o.CrawlerInputs
+}
+
+func validateCrawlerSources(opts Options) error {
+	if len(opts.CrawlerInputs) == 0 {
+		return nil
+	}
+	if !opts.Auto {
+		return fmt.Errorf(`crawler input can only be used in "auto" mode`)
+	}
+	stdinInputs := slices.DeleteFunc(slices.Clone(opts.CrawlerInputs), func(input string) bool {
+		return input != CrawlerInputStdin
+	})
+	if len(stdinInputs) > 1 {
+		return fmt.Errorf("stdin can only be used once as crawler input")
+	}
+	// A single crawler json only lists kernels for a single architecture
+	if len(opts.Archs()) > 1 {
+		for _, input := range opts.CrawlerInputs {
+			if !strings.Contains(input, CrawlerInputArchPlaceholder) {
+				return fmt.Errorf("crawler input %q must contain the %s placeholder when running against multiple architectures", input, CrawlerInputArchPlaceholder)
+			}
+		}
+	}
+	return nil
+}
+
+func isCrawlerURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// openCrawlerSource opens the kernel-crawler json for the current architecture
+// from an url, a local file, or stdin. The caller must close the returned reader.
+func openCrawlerSource(ctx context.Context, opts Options, source string) (io.ReadCloser, error) {
+	// In case testJsonData is set, use it
+	if testJsonData != nil {
+		return io.NopCloser(bytes.NewReader(testJsonData)), nil
+	}
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	if isCrawlerURL(source) {
+		url := strings.ReplaceAll(source, CrawlerInputArchPlaceholder, opts.Architecture.ToNonDeb())
+		root.Printer.Logger.Debug("downloading json data",
+			root.Printer.Logger.Args("url", url))
+		r, err = fetchCrawlerData(ctx, opts, url)
+	} else {
+		root.Printer.Logger.Debug("reading json data",
+			root.Printer.Logger.Args("input", source))
+		r, err = openCrawlerInput(source, opts.Architecture)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if testCacheData {
+		defer r.Close()
+		if testJsonData, err = io.ReadAll(r); err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(testJsonData)), nil
+	}
+	return r, nil
+}
+
+// walkCrawlerEntries streams all the kernel-crawler sources, calling visit, for each distro matching the target filters,
+// with its entries matching the target filters, as soon as they are parsed; entries of non-matching distros are skipped
+// without being materialized, unless needed by sync mode.
+// When multiple sources list the same config (ie: same target, kernel release and kernel version),
+// the entry from the source that comes last wins: sources are walked in reverse order, and only first seen entries are visited.
+// Within a single source, the first listed entry wins.
+// It returns the names of the configs listed by the sources, including filtered out ones.
+func walkCrawlerEntries(ctx context.Context, opts Options, visit func(distro string, entries []crawlerEntry) error) (map[string]struct{}, error) {
+	var (
+		seen    = make(map[string]string)
+		sources = opts.crawlerSources()
+	)
+	for _, source := range slices.Backward(sources) {
+		r, err := openCrawlerSource(ctx, opts, source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load crawler source %s: %w", source, err)
+		}
+		err = decodeCrawlerSource(ctx, opts, r, source, seen, visit)
+		if err == nil {
+			markDecoded(r)
+		}
+		if cErr := r.Close(); err == nil && cErr != nil {
+			err = fmt.Errorf("failed to load crawler source %s: %w", source, cErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(sources) > 1 {
+		root.Printer.Logger.Info("merged crawler sources",
+			root.Printer.Logger.Args("sources", len(sources), "entries", len(seen)))
+	}
+	listed := make(map[string]struct{}, len(seen))
+	for name := range seen {
+		listed[name] = struct{}{}
+	}
+	return listed, nil
+}
+
+// decodeCrawlerSource walks a single kernel-crawler json, made of a map of distros to lists of entries,
+// recording the sources of seen configs in seen.
+func decodeCrawlerSource(ctx context.Context, opts Options, r io.Reader, source string, seen map[string]string, visit func(distro string, entries []crawlerEntry) error) error {
+	var err error
+	iter := json.Parse(json.ConfigDefault, r, crawlerDecodeBufferSize)
+	iter.ReadMapCB(func(iter *json.Iterator, distro string) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		matchingDistro := opts.DistroFilter(distro)
+		if !matchingDistro && !opts.Sync {
+			iter.Skip()
+			return iter.Error == nil
+		}
+		var matchingEntries []crawlerEntry
+		iter.ReadArrayCB(func(iter *json.Iterator) bool {
+			var dkYaml validate.DriverkitYaml
+			iter.ReadVal(&dkYaml)
+			if iter.Error != nil {
+				return false
+			}
+			name := dkYaml.ToConfigName()
+			if winner, ok := seen[name]; ok {
+				if winner != source {
+					root.Printer.Logger.Debug("overriding crawler entry",
+						root.Printer.Logger.Args("config", name, "source", winner, "overridden", source))
+				}
+				return true
+			}
+			seen[name] = source
+			if matchingDistro && opts.KernelReleaseFilter(dkYaml.KernelRelease) && opts.KernelVersionFilter(dkYaml.KernelVersion) {
+				matchingEntries = append(matchingEntries, crawlerEntry{DriverkitYaml: dkYaml, source: source})
+			}
+			return true
+		})
+		if iter.Error != nil {
+			return false
+		}
+		if len(matchingEntries) > 0 {
+			err = visit(distro, matchingEntries)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if iter.Error != nil && !errors.Is(iter.Error, io.EOF) {
+		return fmt.Errorf("failed to parse crawler source %s: %w", source, iter.Error)
+	}
+	return nil
+}
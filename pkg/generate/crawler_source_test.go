@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkCrawlerEntries(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	crawlerDir := t.TempDir()
+	writeInput := func(name, data string) string {
+		path := filepath.Join(crawlerDir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		return path
+	}
+	publicInput := writeInput("public.json", `{
+  "centos": [
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel.rpm"]},
+    {"kernelversion": "1", "kernelrelease": "4.18.0-477.el8.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel-el8.rpm"]},
+    {"kernelversion": "1", "kernelrelease": "4.18.0-477.el8.x86_64", "target": "centos", "headers": ["http://centos/duplicate.rpm"]}
+  ],
+  "ubuntu": [
+    {"kernelversion": "47", "kernelrelease": "5.15.0-1040-aws", "target": "ubuntu-aws", "headers": ["http://ubuntu/linux-headers.deb"]}
+  ]
+}`)
+	internalInput := writeInput("internal.json", `{
+  "centos": [
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://internal/kernel-devel.rpm"]}
+  ]
+}`)
+	truncatedInput := writeInput("truncated.json", `{"centos": [{"kernelversion": "1", "kernelrelease": "5.14.0`)
+	notAMapInput := writeInput("list.json", `[]`)
+	malformedDistroInput := writeInput("malformed.json", `{
+  "debian": {"not": ["a", "list"]},
+  "centos": [
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel.rpm"]}
+  ]
+}`)
+
+	tests := map[string]struct {
+		crawlerInputs []string
+		target        root.Target
+		sync          bool
+		visitErr      error
+		expectError   bool
+		// expectedVisits maps visited distros to visited config names and their headers
+		expectedVisits map[string]map[string]string
+		expectedListed []string
+	}{
+		"non matching distros are skipped": {
+			crawlerInputs: []string{publicInput},
+			target:        root.Target{Distro: "centos", KernelRelease: `^4\.`},
+			expectedVisits: map[string]map[string]string{
+				"centos": {"centos_4.18.0-477.el8.x86_64_1.yaml": "http://centos/kernel-devel-el8.rpm"},
+			},
+			expectedListed: []string{
+				"centos_4.18.0-477.el8.x86_64_1.yaml",
+				"centos_5.14.0-325.el9.x86_64_1.yaml",
+			},
+		},
+		"non matching distros are listed in sync mode": {
+			crawlerInputs: []string{publicInput},
+			target:        root.Target{Distro: "ubuntu"},
+			sync:          true,
+			expectedVisits: map[string]map[string]string{
+				"ubuntu": {"ubuntu-aws_5.15.0-1040-aws_47.yaml": "http://ubuntu/linux-headers.deb"},
+			},
+			expectedListed: []string{
+				"centos_4.18.0-477.el8.x86_64_1.yaml",
+				"centos_5.14.0-325.el9.x86_64_1.yaml",
+				"ubuntu-aws_5.15.0-1040-aws_47.yaml",
+			},
+		},
+		"last source wins": {
+			crawlerInputs: []string{publicInput, internalInput},
+			target:        root.Target{Distro: "centos"},
+			expectedVisits: map[string]map[string]string{
+				"centos": {
+					"centos_4.18.0-477.el8.x86_64_1.yaml": "http://centos/kernel-devel-el8.rpm",
+					"centos_5.14.0-325.el9.x86_64_1.yaml": "http://internal/kernel-devel.rpm",
+				},
+			},
+			expectedListed: []string{
+				"centos_4.18.0-477.el8.x86_64_1.yaml",
+				"centos_5.14.0-325.el9.x86_64_1.yaml",
+			},
+		},
+		"truncated source": {
+			crawlerInputs: []string{truncatedInput},
+			expectError:   true,
+		},
+		"source is not a map": {
+			crawlerInputs: []string{notAMapInput},
+			expectError:   true,
+		},
+		"non matching malformed distro is skipped": {
+			crawlerInputs: []string{malformedDistroInput},
+			target:        root.Target{Distro: "centos"},
+			expectedVisits: map[string]map[string]string{
+				"centos": {"centos_5.14.0-325.el9.x86_64_1.yaml": "http://centos/kernel-devel.rpm"},
+			},
+			expectedListed: []string{"centos_5.14.0-325.el9.x86_64_1.yaml"},
+		},
+		"non matching malformed distro is parsed in sync mode": {
+			crawlerInputs: []string{malformedDistroInput},
+			target:        root.Target{Distro: "centos"},
+			sync:          true,
+			expectError:   true,
+		},
+		"visit error stops the walk": {
+			crawlerInputs: []string{publicInput},
+			target:        root.Target{Distro: "centos"},
+			visitErr:      fmt.Errorf("visit error"),
+			expectError:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{
+				Options: root.Options{
+					Architecture: "amd64",
+					Target:       test.target,
+				},
+				Auto:          true,
+				CrawlerInputs: test.crawlerInputs,
+				Sync:          test.sync,
+			}
+			visits := make(map[string]map[string]string)
+			listed, err := walkCrawlerEntries(context.Background(), opts, func(distro string, entries []crawlerEntry) error {
+				if test.visitErr != nil {
+					return test.visitErr
+				}
+				if visits[distro] == nil {
+					visits[distro] = make(map[string]string)
+				}
+				for _, entry := range entries {
+					visits[distro][entry.ToConfigName()] = entry.KernelUrls[0]
+				}
+				return nil
+			})
+			if test.expectError {
+				assert.Error(t, err)
+				if test.visitErr != nil {
+					assert.ErrorIs(t, err, test.visitErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedVisits, visits)
+			listedNames := make([]string, 0, len(listed))
+			for name := range listed {
+				listedNames = append(listedNames, name)
+			}
+			slices.Sort(listedNames)
+			assert.Equal(t, test.expectedListed, listedNames)
+		})
+	}
+}
+
+func TestWalkCrawlerEntriesCancel(t *testing.T) {
+	withoutTestCrawlerData(t)
+	testJsonData = []byte(testCrawlerData)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := walkCrawlerEntries(ctx, Options{Options: root.Options{Architecture: "amd64"}, Auto: true}, func(string, []crawlerEntry) error {
+		t.Fatal("no entry must be visited once cancelled")
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
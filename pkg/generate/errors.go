@@ -16,10 +16,15 @@ package generate
 
 import (
 	"fmt"
+	"net/http"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
 
 	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
 )
 
+var errStale = fmt.Errorf("%w: stale config, pass --prune to remove it", root.ErrSkipped)
+
 type UnsupportedTargetErr struct {
 	target builder.Type
 }
@@ -27,3 +32,18 @@ type UnsupportedTargetErr struct {
 func (err *UnsupportedTargetErr) Error() string {
 	return fmt.Sprintf("target %s is unsupported by driverkit", err.target.String())
 }
+
+// UnexpectedStatusErr is returned when the kernel-crawler server answers with an unexpected HTTP status.
+type UnexpectedStatusErr struct {
+	url        string
+	statusCode int
+}
+
+func (err *UnexpectedStatusErr) Error() string {
+	return fmt.Sprintf("unexpected status %d (%s) while downloading %s", err.statusCode, http.StatusText(err.statusCode), err.url)
+}
+
+// retryable returns whether the request might succeed if retried later.
+func (err *UnexpectedStatusErr) retryable() bool {
+	return err.statusCode >= http.StatusInternalServerError || err.statusCode == http.StatusTooManyRequests
+}
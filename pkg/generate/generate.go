@@ -15,99 +15,231 @@ limitations under the License.
 package generate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	"github.com/falcosecurity/dbg-go/pkg/validate"
 	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
 	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
-	json "github.com/json-iterator/go"
 	"golang.org/x/sync/errgroup"
 	"gopkg.in/yaml.v3"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
+	"sync"
 )
 
 var (
 	testJsonData  []byte
 	testCacheData bool
+	// loadKernelHeaders is overridden by tests, to avoid hitting the network.
+	loadKernelHeaders = loadKernelHeadersFromDk
 )
 
-func Run(opts Options) error {
+func Run(ctx context.Context, opts Options) error {
 	root.Printer.Logger.Info("generating config files")
-	if opts.Auto {
-		return autogenerateConfigs(opts)
-	} else if opts.IsSet() {
-		return generateSingleConfig(opts)
+	if opts.Host {
+		if opts.Auto || opts.Distro != "" || opts.KernelRelease != "" || opts.KernelVersion != "" {
+			return fmt.Errorf(`"host" cannot be used together with "auto" or target-{distro,kernelrelease,kernelversion}`)
+		}
+		if len(opts.Archs()) > 1 {
+			return fmt.Errorf(`"host" can only be used with a single architecture`)
+		}
+		hostTarget, err := hostTarget(opts.HostFiles)
+		if err != nil {
+			return err
+		}
+		root.Printer.Logger.Info("detected host",
+			root.Printer.Logger.Args(
+				"target", hostTarget.Distro,
+				"kernelrelease", hostTarget.KernelRelease,
+				"kernelversion", hostTarget.KernelVersion))
+		opts.Distro, opts.KernelRelease, opts.KernelVersion = hostTarget.Distro, hostTarget.KernelRelease, hostTarget.KernelVersion
 	}
-	return fmt.Errorf(`either "auto" or target-{distro,kernelrelease,kernelversion} must be passed`)
-}
-
-// This is the only function where opts.Distro gets overridden using KernelCrawler namings
-func autogenerateConfigs(opts Options) error {
-	url := fmt.Sprintf(urlArchFmt, opts.Architecture.ToNonDeb())
-	root.Printer.Logger.Debug("downloading json data",
-		root.Printer.Logger.Args("url", url))
-
-	// Fetch kernel list json
-	var (
-		jsonData []byte
-		err      error
-	)
-
-	// In case testJsonData is set,
-	if testJsonData != nil {
-		jsonData = testJsonData
-	} else {
-		jsonData, err = getURL(url)
+	if len(opts.Targets) > 0 {
+		if opts.Auto || opts.Host || opts.Distro != "" || opts.KernelRelease != "" || opts.KernelVersion != "" {
+			return fmt.Errorf(`targets file cannot be used together with "auto", "host" or target-{distro,kernelrelease,kernelversion}`)
+		}
+		return generateTargets(ctx, opts)
+	}
+	if !opts.Auto && !opts.IsSet() {
+		return fmt.Errorf(`either "auto", a targets file or target-{distro,kernelrelease,kernelversion} must be passed`)
+	}
+	if err := validateCrawlerSources(opts); err != nil {
+		return err
+	}
+	if opts.Sync && !opts.Auto {
+		return fmt.Errorf(`"sync" can only be used in "auto" mode`)
+	}
+	if opts.Retention.IsSet() && !opts.Auto {
+		return fmt.Errorf(`retention can only be used in "auto" mode`)
+	}
+	if opts.Prune && !opts.Sync {
+		return fmt.Errorf(`"prune" can only be used in "sync" mode`)
+	}
+	for _, arch := range opts.Archs() {
+		archOpts := opts
+		archOpts.Options = opts.ForArchitecture(arch)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		if opts.Auto {
+			err = autogenerateConfigs(ctx, archOpts)
+		} else {
+			err = generateSingleConfig(archOpts)
+		}
 		if err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	root.Printer.Logger.Debug("fetched json")
-	if testCacheData {
-		testJsonData = jsonData
+// generateTargets generates the configs of each explicit target, for each architecture,
+// and reports the outcome of each of them in the result details.
+func generateTargets(ctx context.Context, opts Options) error {
+	details := targetsDetails{Targets: make([]targetSummary, 0, len(opts.Targets)*len(opts.Archs()))}
+	var err error
+	for _, arch := range opts.Archs() {
+		archOpts := opts
+		archOpts.Options = opts.ForArchitecture(arch)
+		var summaries []targetSummary
+		summaries, err = generateTargetsConfigs(ctx, archOpts)
+		details.Targets = append(details.Targets, summaries...)
+		if err != nil {
+			break
+		}
 	}
-
-	fullJson := map[string][]validate.DriverkitYaml{}
-
-	// Unmarshal the big json
-	err = json.Unmarshal(jsonData, &fullJson)
+	opts.Result.SetDetails(details)
+	root.Printer.Logger.Info("generated targets",
+		root.Printer.Logger.Args(
+			string(TargetStatusOK), details.count(TargetStatusOK),
+			string(TargetStatusWarning), details.count(TargetStatusWarning),
+			string(TargetStatusUnsupported), details.count(TargetStatusUnsupported),
+			string(TargetStatusFailed), details.count(TargetStatusFailed)))
 	if err != nil {
 		return err
 	}
-	root.Printer.Logger.Debug("unmarshalled json")
-	var errGrp errgroup.Group
-
-	for distro, f := range fullJson {
-		kernelEntries := f
-
-		// Skip unneeded kernel entries
-		// optimization for target-distro: skip entire key
-		// instead of skipping objects one by one.
-		if !opts.DistroFilter(distro) {
-			continue
-		}
+	if unsupported := details.count(TargetStatusUnsupported); unsupported > 0 {
+		return fmt.Errorf("%d targets are unsupported by driverkit", unsupported)
+	}
+	return nil
+}
 
-		// A goroutine for each distro
+// This is the only function where opts.Distro gets overridden using KernelCrawler namings
+func autogenerateConfigs(ctx context.Context, opts Options) error {
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errGrp, errGrpCtx := errgroup.WithContext(walkCtx)
+	// Configs of kernels dropped by the retention policy
+	var (
+		retired   = make(map[string]struct{})
+		retiredMu sync.Mutex
+	)
+	generateEntries := func(distro string, kernelEntries []crawlerEntry) {
+		// A goroutine for each batch of distro entries
 		errGrp.Go(func() error {
-			for _, kernelEntry := range kernelEntries {
-				if !opts.KernelReleaseFilter(kernelEntry.KernelRelease) {
-					continue
+			retainedEntries, droppedEntries := opts.Retention.apply(kernelEntries)
+			if len(droppedEntries) > 0 {
+				root.Printer.Logger.Info("skipping kernels because of retention",
+					root.Printer.Logger.Args("distro", distro, "kernels", len(droppedEntries)))
+				retiredMu.Lock()
+				for _, entry := range droppedEntries {
+					retired[entry.ToConfigName()] = struct{}{}
 				}
-				if !opts.KernelVersionFilter(kernelEntry.KernelVersion) {
-					continue
+				retiredMu.Unlock()
+			}
+
+			for _, kernelEntry := range retainedEntries {
+				// Stop as soon as the run is interrupted, or another distro failed
+				if err := errGrpCtx.Err(); err != nil {
+					return err
 				}
-				if pvtErr := dumpConfig(opts, kernelEntry); pvtErr != nil {
+				if pvtErr := dumpConfig(opts, kernelEntry.DriverkitYaml, kernelEntry.source); pvtErr != nil {
 					return pvtErr
 				}
 			}
 			return nil
 		})
 	}
-	return errGrp.Wait()
+
+	// Stream kernel list jsons, generating matching entries while the rest is still being parsed;
+	// retention needs all the entries of a distro, from all the sources, before being applied.
+	pendingEntries := make(map[string][]crawlerEntry)
+	listed, err := walkCrawlerEntries(errGrpCtx, opts, func(distro string, kernelEntries []crawlerEntry) error {
+		if opts.Retention.IsSet() {
+			pendingEntries[distro] = append(pendingEntries[distro], kernelEntries...)
+		} else {
+			generateEntries(distro, kernelEntries)
+		}
+		return nil
+	})
+	if err == nil {
+		root.Printer.Logger.Debug("loaded json")
+		for _, distro := range slices.Sorted(maps.Keys(pendingEntries)) {
+			generateEntries(distro, pendingEntries[distro])
+		}
+	} else {
+		// Stop generating entries of a broken source
+		cancel()
+	}
+	// A failing worker cancels the walk: report its error, rather than the cancellation
+	if wErr := errGrp.Wait(); wErr != nil && (err == nil || errors.Is(err, context.Canceled)) {
+		return wErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if opts.Sync {
+		return syncStaleConfigs(ctx, opts, listed, retired)
+	}
+	return nil
+}
+
+// syncStaleConfigs reports, or removes when pruning, existing configs matching the target filters
+// whose kernel is not listed by any kernel-crawler entry anymore, or was retired by the retention policy.
+// Kernels that are listed but filtered out, eg: by distro, are never considered stale.
+func syncStaleConfigs(ctx context.Context, opts Options, listed, retired map[string]struct{}) error {
+	for name := range retired {
+		delete(listed, name)
+	}
+	configs, err := root.ListFiltered(root.BuildConfigPath, opts.Options, actionRemoving)
+	if err != nil {
+		return err
+	}
+	for _, entry := range configs {
+		if _, ok := listed[filepath.Base(entry.Path)]; ok {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		switch {
+		case !opts.Prune:
+			root.Printer.Logger.Info("stale config",
+				root.Printer.Logger.Args("config", entry.Path))
+			opts.Result.Record(entry, errStale)
+		case opts.DryRun:
+			root.Printer.Logger.Info("skipping because of dry-run.",
+				root.Printer.Logger.Args("config", entry.Path))
+			opts.Result.Plan(entry)
+		default:
+			root.Printer.Logger.Info("removing stale config",
+				root.Printer.Logger.Args("config", entry.Path))
+			err = os.Remove(entry.Path)
+			opts.Result.Record(entry, err)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func loadKernelHeadersFromDk(opts Options) ([]string, error) {
@@ -149,13 +281,22 @@ func loadKernelHeadersFromDk(opts Options) ([]string, error) {
 }
 
 func generateSingleConfig(opts Options) error {
-	kernelheaders, err := loadKernelHeadersFromDk(opts)
+	_, err := generateTargetConfig(opts)
+	return err
+}
+
+// generateTargetConfig generates the configs of the explicit target in opts.
+// Kernel headers that cannot be loaded through driverkit are not fatal:
+// configs are written with empty headers, and the error is returned as warning.
+func generateTargetConfig(opts Options) (warning error, err error) {
+	kernelheaders, err := loadKernelHeaders(opts)
 	if err != nil {
 		var unsupportedTargetError *UnsupportedTargetErr
 		if errors.As(err, &unsupportedTargetError) {
-			return unsupportedTargetError
+			return nil, unsupportedTargetError
 		}
 		root.Printer.Logger.Warn(err.Error())
+		warning = err
 	}
 	driverkitYaml := validate.DriverkitYaml{
 		KernelVersion: opts.KernelVersion,
@@ -163,45 +304,78 @@ func generateSingleConfig(opts Options) error {
 		Target:        opts.Distro.String(),
 		KernelUrls:    kernelheaders,
 	}
-	return dumpConfig(opts, driverkitYaml)
+	return warning, dumpConfig(opts, driverkitYaml, "")
 }
 
-func dumpConfig(opts Options, dkYaml validate.DriverkitYaml) error {
-	root.Printer.Logger.Info("generating",
-		root.Printer.Logger.Args(
-			"target", dkYaml.Target,
-			"kernelrelease", dkYaml.KernelRelease,
-			"kernelversion", dkYaml.KernelVersion))
-	if opts.DryRun {
-		root.Printer.Logger.Info("skipping because of dry-run.")
-		return nil
+// dumpConfig writes the config for each driver version; source is the kernel-crawler source
+// the config comes from, if any, and is only recorded in the result, to keep configs independent of it.
+func dumpConfig(opts Options, dkYaml validate.DriverkitYaml, source string) error {
+	args := []any{
+		"target", dkYaml.Target,
+		"kernelrelease", dkYaml.KernelRelease,
+		"kernelversion", dkYaml.KernelVersion,
 	}
-
+	if source != "" {
+		args = append(args, "source", source)
+	}
+	root.Printer.Logger.Info("generating", root.Printer.Logger.Args(args...))
 	dkYaml.Architecture = opts.Architecture.String()
 
 	// Sort kernelurls, so that we always get the same sorting for dbg configs.
 	slices.Sort(dkYaml.KernelUrls)
 
 	for _, driverVersion := range opts.DriverVersion {
+		configPath := root.BuildConfigPath(opts.Options, driverVersion, dkYaml.ToConfigName())
+		entry := root.ResultItem{
+			Action:        actionGenerating,
+			Architecture:  opts.Architecture,
+			DriverVersion: driverVersion,
+			Path:          configPath,
+			Source:        source,
+		}
 		dkYaml.FillOutputs(driverVersion, opts.Options)
-		yamlData, pvtErr := yaml.Marshal(&dkYaml)
-		if pvtErr != nil {
-			return pvtErr
+		opts.Overlays.Apply(&dkYaml)
+		yamlData, err := yaml.Marshal(&dkYaml)
+		if err != nil {
+			opts.Result.Record(entry, err)
+			return err
 		}
 
-		configPath := root.BuildConfigPath(opts.Options, driverVersion, dkYaml.ToConfigName())
+		action, err := root.WriteAction(configPath, yamlData)
+		if err != nil {
+			opts.Result.Record(entry, err)
+			return err
+		}
+		if action == "" {
+			// Keep reruns cheap and diffs clean
+			root.Printer.Logger.Debug("config unchanged",
+				root.Printer.Logger.Args("config", configPath))
+			opts.Result.Record(entry, root.ErrUnchanged)
+			continue
+		}
+		entry.Action = action
 
-		// Make sure folder exists
-		pvtErr = os.MkdirAll(filepath.Dir(configPath), os.ModePerm)
-		if pvtErr != nil {
-			return pvtErr
+		if opts.DryRun {
+			root.Printer.Logger.Info("skipping because of dry-run.",
+				root.Printer.Logger.Args("config", configPath))
+			opts.Result.Plan(entry)
+			continue
 		}
-		fW, pvtErr := os.OpenFile(configPath, os.O_CREATE|os.O_RDWR, os.ModePerm)
+
+		pvtErr := writeConfig(configPath, yamlData)
+		opts.Result.Record(entry, pvtErr)
 		if pvtErr != nil {
 			return pvtErr
 		}
-		_, _ = fW.Write(yamlData)
-		_ = fW.Close()
 	}
 	return nil
 }
+
+func writeConfig(configPath string, yamlData []byte) error {
+	// Make sure folder exists
+	err := os.MkdirAll(filepath.Dir(configPath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	return root.WriteFileAtomic(configPath, yamlData, 0o644)
+}
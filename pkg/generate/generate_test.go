@@ -15,16 +15,40 @@ limitations under the License.
 package generate
 
 import (
+	"context"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	"github.com/falcosecurity/dbg-go/pkg/stats"
 	testutils "github.com/falcosecurity/dbg-go/pkg/utils/test"
 	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
+func TestMain(m *testing.M) {
+	// Do not wait between download attempts, eg: when running offline
+	crawlerRetryBackoff = time.Millisecond
+	os.Exit(m.Run())
+}
+
+// withoutTestCrawlerData makes sure that, for the whole test, neither cached json data is used,
+// nor the crawler input gets cached.
+func withoutTestCrawlerData(t *testing.T) {
+	t.Helper()
+	jsonData, cacheData := testJsonData, testCacheData
+	testJsonData, testCacheData = nil, false
+	t.Cleanup(func() {
+		testJsonData, testCacheData = jsonData, cacheData
+	})
+}
+
 func BenchmarkAutogenerate(b *testing.B) {
 	testCacheData = true // enable json data caching for subsequent tests
 	opts := Options{
@@ -40,7 +64,7 @@ func BenchmarkAutogenerate(b *testing.B) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		err := Run(opts)
+		err := Run(context.Background(), opts)
 		assert.NoError(b, err)
 		_ = os.RemoveAll("./test/")
 	}
@@ -258,7 +282,7 @@ func TestGenerate(t *testing.T) {
 				_ = os.RemoveAll(test.opts.RepoRoot)
 			})
 			assert.NoError(t, err)
-			err = Run(test.opts)
+			err = Run(context.Background(), test.opts)
 			if test.expectError {
 				assert.Error(t, err)
 			} else {
@@ -273,15 +297,467 @@ func TestGenerate(t *testing.T) {
 				statsOpts.KernelRelease = ""
 				statsOpts.Distro = ""
 
-				driverStats, err := statter.GetDriverStats(statsOpts)
+				driverStats, err := statter.GetDriverStats(context.Background(), statsOpts)
 				assert.NoError(t, err)
 				assert.GreaterOrEqual(t, len(driverStats), test.expectedMinConfigs)
 
 				// Validate all generated files
 				validateOpts := validate.Options{Options: test.opts.Options}
-				err = validate.Run(validateOpts)
+				err = validate.Run(context.Background(), validateOpts)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateFromCrawlerInput(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	crawlerDir := t.TempDir()
+	crawlerJson := `{
+  "centos": [
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel.rpm"]},
+    {"kernelversion": "1", "kernelrelease": "4.18.0-477.el8.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel-el8.rpm"]}
+  ],
+  "ubuntu": [
+    {"kernelversion": "47", "kernelrelease": "5.15.0-1040-aws", "target": "ubuntu-aws", "headers": ["http://ubuntu/linux-headers.deb"]}
+  ]
+}`
+	assert.NoError(t, os.MkdirAll(filepath.Join(crawlerDir, "x86_64"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(crawlerDir, "x86_64", "list.json"), []byte(crawlerJson), 0o644))
+	// Custom kernels, overriding one public entry
+	internalJson := `{
+  "centos": [
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://internal/kernel-devel.rpm"]},
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.patched.x86_64", "target": "centos", "headers": ["http://internal/kernel-devel-patched.rpm"]}
+  ]
+}`
+	assert.NoError(t, os.WriteFile(filepath.Join(crawlerDir, "x86_64", "internal.json"), []byte(internalJson), 0o644))
+
+	tests := map[string]struct {
+		crawlerInputs   []string
+		target          root.Target
+		architectures   []kernelrelease.Architecture
+		auto            bool
+		expectError     bool
+		expectedConfigs []string
+		// expectedSources maps config names to the expected crawler source, when checked
+		expectedSources map[string]string
+	}{
+		"all entries": {
+			crawlerInputs: []string{filepath.Join(crawlerDir, "x86_64", "list.json")},
+			auto:          true,
+			expectedConfigs: []string{
+				"centos_4.18.0-477.el8.x86_64_1.yaml",
+				"centos_5.14.0-325.el9.x86_64_1.yaml",
+				"ubuntu-aws_5.15.0-1040-aws_47.yaml",
+			},
+		},
+		"filtered entries": {
+			crawlerInputs: []string{filepath.Join(crawlerDir, "x86_64", "list.json")},
+			target: root.Target{
+				Distro:        "centos",
+				KernelRelease: `^5\..+$`,
+			},
+			auto:            true,
+			expectedConfigs: []string{"centos_5.14.0-325.el9.x86_64_1.yaml"},
+		},
+		"arch placeholder": {
+			crawlerInputs:   []string{filepath.Join(crawlerDir, CrawlerInputArchPlaceholder, "list.json")},
+			target:          root.Target{Distro: "ubuntu"},
+			auto:            true,
+			expectedConfigs: []string{"ubuntu-aws_5.15.0-1040-aws_47.yaml"},
+		},
+		"merged crawler inputs": {
+			crawlerInputs: []string{
+				filepath.Join(crawlerDir, "x86_64", "list.json"),
+				filepath.Join(crawlerDir, "x86_64", "internal.json"),
+			},
+			target: root.Target{Distro: "centos"},
+			auto:   true,
+			expectedConfigs: []string{
+				"centos_4.18.0-477.el8.x86_64_1.yaml",
+				"centos_5.14.0-325.el9.x86_64_1.yaml",
+				"centos_5.14.0-325.patched.x86_64_1.yaml",
+			},
+			expectedSources: map[string]string{
+				"centos_4.18.0-477.el8.x86_64_1.yaml":     filepath.Join(crawlerDir, "x86_64", "list.json"),
+				"centos_5.14.0-325.el9.x86_64_1.yaml":     filepath.Join(crawlerDir, "x86_64", "internal.json"),
+				"centos_5.14.0-325.patched.x86_64_1.yaml": filepath.Join(crawlerDir, "x86_64", "internal.json"),
+			},
+		},
+		"merged crawler inputs, reversed precedence": {
+			crawlerInputs: []string{
+				filepath.Join(crawlerDir, "x86_64", "internal.json"),
+				filepath.Join(crawlerDir, "x86_64", "list.json"),
+			},
+			target: root.Target{Distro: "centos", KernelRelease: "5.14.0-325.el9.x86_64"},
+			auto:   true,
+			expectedConfigs: []string{
+				"centos_5.14.0-325.el9.x86_64_1.yaml",
+			},
+			expectedSources: map[string]string{
+				"centos_5.14.0-325.el9.x86_64_1.yaml": filepath.Join(crawlerDir, "x86_64", "list.json"),
+			},
+		},
+		"stdin used twice": {
+			crawlerInputs: []string{CrawlerInputStdin, CrawlerInputStdin},
+			auto:          true,
+			expectError:   true,
+		},
+		"missing crawler input": {
+			crawlerInputs: []string{filepath.Join(crawlerDir, "missing.json")},
+			auto:          true,
+			expectError:   true,
+		},
+		"crawler input without auto mode": {
+			crawlerInputs: []string{filepath.Join(crawlerDir, "x86_64", "list.json")},
+			target: root.Target{
+				Distro:        "centos",
+				KernelRelease: "5.14.0-325.el9.x86_64",
+				KernelVersion: "1",
+			},
+			expectError: true,
+		},
+		"multiple architectures without arch placeholder": {
+			crawlerInputs: []string{filepath.Join(crawlerDir, "x86_64", "list.json")},
+			architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+			auto:          true,
+			expectError:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{
+				Options: root.Options{
+					RepoRoot:      t.TempDir(),
+					Architecture:  "amd64",
+					Architectures: test.architectures,
+					DriverVersion: []string{"1.0.0+driver"},
+					DriverName:    "falco",
+					Target:        test.target,
+				},
+				Auto:          test.auto,
+				CrawlerInputs: test.crawlerInputs,
+			}
+			opts.Result = root.NewResult()
+			err := Run(context.Background(), opts)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			configs, err := filepath.Glob(root.BuildConfigPath(opts.Options, "1.0.0+driver", "*.yaml"))
+			assert.NoError(t, err)
+			configNames := make([]string, 0, len(configs))
+			for _, config := range configs {
+				configNames = append(configNames, filepath.Base(config))
+			}
+			assert.ElementsMatch(t, test.expectedConfigs, configNames)
+
+			checkedSources := 0
+			for _, item := range opts.Result.Items() {
+				expectedSource, ok := test.expectedSources[filepath.Base(item.Path)]
+				if !ok {
+					continue
+				}
+				checkedSources++
+				assert.Equal(t, expectedSource, item.Source)
+				data, err := os.ReadFile(item.Path)
 				assert.NoError(t, err)
+				// The source is not recorded in configs, so that they do not change along with it
+				assert.NotContains(t, string(data), expectedSource)
+				var dkYaml validate.DriverkitYaml
+				assert.NoError(t, yaml.Unmarshal(data, &dkYaml))
+				if strings.HasSuffix(expectedSource, "internal.json") {
+					assert.Contains(t, dkYaml.KernelUrls[0], "internal")
+				} else {
+					assert.NotContains(t, dkYaml.KernelUrls[0], "internal")
+				}
 			}
+			assert.Equal(t, len(test.expectedSources), checkedSources)
 		})
 	}
 }
+
+func TestGenerateSync(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	crawlerInput := filepath.Join(t.TempDir(), "list.json")
+	crawlerJson := `{
+  "centos": [
+    {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel.rpm"]},
+    {"kernelversion": "1", "kernelrelease": "4.18.0-477.el8.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel-el8.rpm"]},
+    {"kernelversion": "1", "kernelrelease": "4.18.0-500.el8.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel-500.rpm"]}
+  ],
+  "ubuntu": [
+    {"kernelversion": "47", "kernelrelease": "5.15.0-1040-aws", "target": "ubuntu-aws", "headers": ["http://ubuntu/linux-headers.deb"]}
+  ]
+}`
+	assert.NoError(t, os.WriteFile(crawlerInput, []byte(crawlerJson), 0o644))
+
+	const (
+		unchangedConfig  = "centos_5.14.0-325.el9.x86_64_1.yaml"
+		changedConfig    = "centos_4.18.0-477.el8.x86_64_1.yaml"
+		addedConfig      = "centos_4.18.0-500.el8.x86_64_1.yaml"
+		staleConfig      = "centos_3.10.0-1160.el7.x86_64_1.yaml"
+		outOfScopeConfig = "debian_4.19.0-26-amd64_1.yaml"
+		filteredConfig   = "ubuntu-aws_5.15.0-1040-aws_47.yaml"
+	)
+
+	tests := map[string]struct {
+		prune           bool
+		dryRun          bool
+		expectedActions map[string]string
+		expectedOutcome map[string]root.Outcome
+		expectedConfigs []string
+	}{
+		"sync": {
+			expectedActions: map[string]string{
+				unchangedConfig: actionGenerating,
+				changedConfig:   root.ActionUpdating,
+				addedConfig:     root.ActionCreating,
+				staleConfig:     actionRemoving,
+			},
+			expectedOutcome: map[string]root.Outcome{
+				unchangedConfig: root.OutcomeSkipped,
+				changedConfig:   root.OutcomeSucceeded,
+				addedConfig:     root.OutcomeSucceeded,
+				staleConfig:     root.OutcomeSkipped,
+			},
+			expectedConfigs: []string{unchangedConfig, changedConfig, addedConfig, staleConfig, outOfScopeConfig, filteredConfig},
+		},
+		"sync and prune": {
+			prune: true,
+			expectedActions: map[string]string{
+				unchangedConfig: actionGenerating,
+				changedConfig:   root.ActionUpdating,
+				addedConfig:     root.ActionCreating,
+				staleConfig:     actionRemoving,
+			},
+			expectedOutcome: map[string]root.Outcome{
+				unchangedConfig: root.OutcomeSkipped,
+				changedConfig:   root.OutcomeSucceeded,
+				addedConfig:     root.OutcomeSucceeded,
+				staleConfig:     root.OutcomeSucceeded,
+			},
+			expectedConfigs: []string{unchangedConfig, changedConfig, addedConfig, outOfScopeConfig, filteredConfig},
+		},
+		"sync and prune in dry-run mode": {
+			prune:  true,
+			dryRun: true,
+			expectedActions: map[string]string{
+				unchangedConfig: actionGenerating,
+				changedConfig:   root.ActionUpdating,
+				addedConfig:     root.ActionCreating,
+				staleConfig:     actionRemoving,
+			},
+			expectedOutcome: map[string]root.Outcome{
+				unchangedConfig: root.OutcomeSkipped,
+				changedConfig:   root.OutcomePlanned,
+				addedConfig:     root.OutcomePlanned,
+				staleConfig:     root.OutcomePlanned,
+			},
+			expectedConfigs: []string{unchangedConfig, changedConfig, staleConfig, outOfScopeConfig, filteredConfig},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{
+				Options: root.Options{
+					RepoRoot:      t.TempDir(),
+					Architecture:  "amd64",
+					DriverVersion: []string{"1.0.0+driver"},
+					DriverName:    "falco",
+					Target:        root.Target{Distro: "centos"},
+				},
+				Auto:          true,
+				CrawlerInputs: []string{crawlerInput},
+			}
+
+			// Generate the current configs, then simulate an outdated tree
+			assert.NoError(t, Run(context.Background(), opts))
+			configPath := func(name string) string {
+				return root.BuildConfigPath(opts.Options, "1.0.0+driver", name)
+			}
+			assert.NoError(t, os.Remove(configPath(addedConfig)))
+			assert.NoError(t, os.WriteFile(configPath(changedConfig), []byte("kernelversion: \"0\"\n"), 0o644))
+			for _, config := range []string{staleConfig, outOfScopeConfig, filteredConfig} {
+				assert.NoError(t, os.WriteFile(configPath(config), []byte("kernelversion: \"1\"\n"), 0o644))
+			}
+
+			opts.Result = root.NewResult()
+			opts.DryRun = test.dryRun
+			opts.Sync = true
+			opts.Prune = test.prune
+			assert.NoError(t, Run(context.Background(), opts))
+
+			actions := make(map[string]string)
+			outcomes := make(map[string]root.Outcome)
+			for _, item := range opts.Result.Items() {
+				actions[filepath.Base(item.Path)] = item.Action
+				outcomes[filepath.Base(item.Path)] = item.Outcome
+			}
+			assert.Equal(t, test.expectedActions, actions)
+			assert.Equal(t, test.expectedOutcome, outcomes)
+
+			configs, err := filepath.Glob(configPath("*.yaml"))
+			assert.NoError(t, err)
+			configNames := make([]string, 0, len(configs))
+			for _, config := range configs {
+				configNames = append(configNames, filepath.Base(config))
+			}
+			assert.ElementsMatch(t, test.expectedConfigs, configNames)
+		})
+	}
+}
+
+func TestGenerateSyncOptions(t *testing.T) {
+	opts := Options{
+		Options: root.Options{
+			Architecture:  "amd64",
+			DriverVersion: []string{"1.0.0+driver"},
+			Target: root.Target{
+				Distro:        "centos",
+				KernelRelease: "5.14.0-325.el9.x86_64",
+				KernelVersion: "1",
+			},
+		},
+		Sync: true,
+	}
+	assert.Error(t, Run(context.Background(), opts))
+
+	opts.Auto, opts.Sync, opts.Prune = true, false, true
+	assert.Error(t, Run(context.Background(), opts))
+}
+
+func TestGenerateRerun(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	crawlerInput := filepath.Join(t.TempDir(), "list.json")
+	crawlerJson := `{"centos": [{"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel.rpm"]}]}`
+	assert.NoError(t, os.WriteFile(crawlerInput, []byte(crawlerJson), 0o644))
+
+	opts := Options{
+		Options: root.Options{
+			RepoRoot:      t.TempDir(),
+			Architecture:  "amd64",
+			DriverVersion: []string{"1.0.0+driver"},
+			DriverName:    "falco",
+		},
+		Auto:          true,
+		CrawlerInputs: []string{crawlerInput},
+	}
+	configPath := root.BuildConfigPath(opts.Options, "1.0.0+driver", "centos_5.14.0-325.el9.x86_64_1.yaml")
+
+	run := func(t *testing.T) []root.ResultItem {
+		opts.Result = root.NewResult()
+		assert.NoError(t, Run(context.Background(), opts))
+		items := opts.Result.Items()
+		assert.Len(t, items, 1)
+		return items
+	}
+
+	items := run(t)
+	assert.Equal(t, root.ActionCreating, items[0].Action)
+	assert.Equal(t, root.OutcomeSucceeded, items[0].Outcome)
+	expected, err := os.ReadFile(configPath)
+	assert.NoError(t, err)
+	info, err := os.Stat(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	// Unchanged configs are not rewritten
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	assert.NoError(t, os.Chtimes(configPath, past, past))
+	items = run(t)
+	assert.Equal(t, actionGenerating, items[0].Action)
+	assert.Equal(t, root.OutcomeSkipped, items[0].Outcome)
+	info, err = os.Stat(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, past, info.ModTime())
+
+	// A longer existing config is fully replaced, without trailing garbage
+	assert.NoError(t, os.WriteFile(configPath, append(slices.Clone(expected), []byte("trailing: garbage\n")...), 0o644))
+	items = run(t)
+	assert.Equal(t, root.ActionUpdating, items[0].Action)
+	assert.Equal(t, root.OutcomeSucceeded, items[0].Outcome)
+	data, err := os.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(data))
+
+	// Write errors are surfaced
+	assert.NoError(t, os.Remove(configPath))
+	assert.NoError(t, os.MkdirAll(filepath.Join(configPath, "dir"), 0o755))
+	opts.Result = root.NewResult()
+	assert.Error(t, Run(context.Background(), opts))
+	items = opts.Result.Items()
+	assert.Len(t, items, 1)
+	assert.Equal(t, root.OutcomeFailed, items[0].Outcome)
+
+	// No temporary file is left behind
+	leftovers, err := filepath.Glob(filepath.Join(filepath.Dir(configPath), ".*"))
+	assert.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestGenerateOverlays(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	crawlerInput := filepath.Join(t.TempDir(), "list.json")
+	crawlerJson := `{"centos": [
+  {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel.rpm"]},
+  {"kernelversion": "1", "kernelrelease": "4.18.0-477.el8.x86_64", "target": "centos", "headers": ["http://centos/kernel-devel-el8.rpm"]}
+]}`
+	assert.NoError(t, os.WriteFile(crawlerInput, []byte(crawlerJson), 0o644))
+	overlays, err := validate.ParseOverlays([]byte(`
+overlays:
+  - distro: ^centos$
+    kernelrelease: ^5\.14\.
+    kernelconfigdata: Q09ORklHX1RFU1Q9eQo=
+    kernelurls:
+      - http://internal/kernel-devel.rpm
+    disableprobe: true
+`))
+	assert.NoError(t, err)
+
+	opts := Options{
+		Options: root.Options{
+			RepoRoot:      t.TempDir(),
+			Architecture:  "amd64",
+			DriverVersion: []string{"1.0.0+driver", "2.0.0+driver"},
+			DriverName:    "falco",
+			Result:        root.NewResult(),
+		},
+		Auto:          true,
+		CrawlerInputs: []string{crawlerInput},
+		Overlays:      overlays,
+	}
+	assert.NoError(t, Run(context.Background(), opts))
+
+	for _, driverVersion := range opts.DriverVersion {
+		var dkYaml validate.DriverkitYaml
+		data, err := os.ReadFile(root.BuildConfigPath(opts.Options, driverVersion, "centos_5.14.0-325.el9.x86_64_1.yaml"))
+		assert.NoError(t, err)
+		assert.NoError(t, yaml.Unmarshal(data, &dkYaml))
+		assert.Equal(t, "Q09ORklHX1RFU1Q9eQo=", dkYaml.KernelConfigData)
+		assert.Equal(t, []string{"http://centos/kernel-devel.rpm", "http://internal/kernel-devel.rpm"}, dkYaml.KernelUrls)
+		assert.Empty(t, dkYaml.Output.Probe)
+		assert.Contains(t, dkYaml.Output.Module, driverVersion)
+
+		data, err = os.ReadFile(root.BuildConfigPath(opts.Options, driverVersion, "centos_4.18.0-477.el8.x86_64_1.yaml"))
+		assert.NoError(t, err)
+		dkYaml = validate.DriverkitYaml{}
+		assert.NoError(t, yaml.Unmarshal(data, &dkYaml))
+		assert.Empty(t, dkYaml.KernelConfigData)
+		assert.NotEmpty(t, dkYaml.Output.Probe)
+	}
+
+	// Generated configs do not drift from overlays
+	validateOpts := validate.Options{Options: opts.Options, Overlays: overlays}
+	validateOpts.Result = root.NewResult()
+	assert.NoError(t, validate.Run(context.Background(), validateOpts))
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+)
+
+// HostFiles are the files describing the host to generate a config for.
+type HostFiles struct {
+	// OSRelease is the os-release file, ie: /etc/os-release.
+	OSRelease string
+	// KernelRelease holds the kernel release, ie: what `uname -r` returns.
+	KernelRelease string
+	// KernelVersion holds the kernel version, ie: what `uname -v` returns.
+	KernelVersion string
+}
+
+// DefaultHostFiles describe the running host.
+var DefaultHostFiles = HostFiles{
+	OSRelease:     "/etc/os-release",
+	KernelRelease: "/proc/sys/kernel/osrelease",
+	KernelVersion: "/proc/sys/kernel/version",
+}
+
+var (
+	// Eg: "5.15.0-1040-aws" -> aws
+	ubuntuFlavorRegex = regexp.MustCompile(`-([a-zA-Z]+)(-.*)?$`)
+	// Eg: "#1 SMP Debian 5.10.178-3 (2023-04-22)" -> 1
+	kernelVersionRegex = regexp.MustCompile(`#(\d+)`)
+	// Eg: "5.10.0-0.deb10.22-rt-amd64" -> "rt-", "amd64"
+	debianKernelReleaseRegex = regexp.MustCompile(`-?(rt-|cloud-|)(amd64|arm64)`)
+	// Eg: "#1 SMP PREEMPT_RT Debian 5.10.178-3 (2023-04-22)" -> "5.10.178-3"
+	debianKernelVersionRegex = regexp.MustCompile(`\d+\.\d+\.\d+-\d+`)
+)
+
+// hostTarget returns the target describing the host: the driverkit target it maps to,
+// its kernel release, and its kernel version, all following kernel-crawler conventions.
+func hostTarget(files HostFiles) (root.Target, error) {
+	osRelease, err := readOSRelease(files.OSRelease)
+	if err != nil {
+		return root.Target{}, err
+	}
+	kernelRelease, err := readHostFile(files.KernelRelease)
+	if err != nil {
+		return root.Target{}, err
+	}
+	kernelVersion, err := readHostFile(files.KernelVersion)
+	if err != nil {
+		return root.Target{}, err
+	}
+
+	target := root.Target{
+		KernelRelease: kernelRelease,
+		KernelVersion: "1",
+	}
+	if matches := kernelVersionRegex.FindStringSubmatch(kernelVersion); matches != nil {
+		target.KernelVersion = matches[1]
+	}
+
+	id := strings.ToLower(osRelease["ID"])
+	switch id {
+	case "ubuntu":
+		// Eg: "#26~22.04.1-Ubuntu SMP Mon Apr 24 01:58:15 UTC 2023" -> 26~22.04.1
+		kv, _, _ := strings.Cut(strings.TrimLeft(kernelVersion, "#"), " ")
+		target.KernelVersion = strings.TrimSuffix(kv, "-Ubuntu")
+		flavor := "generic"
+		if matches := ubuntuFlavorRegex.FindStringSubmatch(kernelrelease.FromString(kernelRelease).FullExtraversion); matches != nil {
+			flavor = matches[1]
+		}
+		target.Distro = builder.Type("ubuntu-" + flavor)
+	case "amzn":
+		switch osRelease["VERSION_ID"] {
+		case "2":
+			target.Distro = builder.TargetTypeAmazonLinux2
+		case "2022":
+			target.Distro = builder.TargetTypeAmazonLinux2022
+		case "2023":
+			target.Distro = builder.TargetTypeAmazonLinux2023
+		default:
+			target.Distro = builder.TargetTypeAmazonLinux
+		}
+	case "debian":
+		target.Distro = builder.TargetTypeDebian
+		// Debian kernel releases are the ABI of the kernel package; the real one is in the kernel version.
+		// Eg: "5.10.0-0.deb10.22-rt-amd64", "#1 SMP PREEMPT_RT Debian 5.10.178-3" -> "5.10.178-3-rt-amd64"
+		if realRelease := debianKernelVersionRegex.FindString(kernelVersion); realRelease != "" {
+			if matches := debianKernelReleaseRegex.FindStringSubmatch(kernelrelease.FromString(kernelRelease).FullExtraversion); matches != nil {
+				realRelease += "-" + matches[1] + matches[2]
+			}
+			target.KernelRelease = realRelease
+			target.KernelVersion = "1"
+		}
+	default:
+		target.Distro = builder.Type(id)
+	}
+
+	supportedDistro := target.Distro
+	if strings.HasPrefix(supportedDistro.String(), builder.TargetTypeUbuntu.String()) {
+		supportedDistro = builder.TargetTypeUbuntu
+	}
+	if _, ok := root.SupportedDistros[supportedDistro]; !ok {
+		return root.Target{}, fmt.Errorf("host distro %q is not supported; supported: %s", id, strings.Join(root.SupportedDistroSlice, ","))
+	}
+	return target, nil
+}
+
+func readHostFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return value, nil
+}
+
+// readOSRelease parses an os-release file, made of KEY=value lines, where values may be quoted.
+func readOSRelease(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	if values["ID"] == "" {
+		return nil, fmt.Errorf("no ID found in %s", path)
+	}
+	return values, scanner.Err()
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostTarget(t *testing.T) {
+	tests := map[string]struct {
+		osRelease     string
+		kernelRelease string
+		kernelVersion string
+		expectError   bool
+		expected      root.Target
+	}{
+		"ubuntu aws": {
+			osRelease:     "NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"22.04\"\n",
+			kernelRelease: "5.15.0-1040-aws",
+			kernelVersion: "#45~20.04.1-Ubuntu SMP Tue Jul 11 19:08:19 UTC 2023",
+			expected:      root.Target{Distro: "ubuntu-aws", KernelRelease: "5.15.0-1040-aws", KernelVersion: "45~20.04.1"},
+		},
+		"ubuntu generic": {
+			osRelease:     "ID=ubuntu\n",
+			kernelRelease: "5.15.0-76-generic",
+			kernelVersion: "#83-Ubuntu SMP Thu Jun 15 19:16:32 UTC 2023",
+			expected:      root.Target{Distro: "ubuntu-generic", KernelRelease: "5.15.0-76-generic", KernelVersion: "83"},
+		},
+		"amazonlinux2": {
+			osRelease:     "NAME=\"Amazon Linux\"\nID=\"amzn\"\nVERSION_ID=\"2\"\n",
+			kernelRelease: "5.10.184-175.731.amzn2.x86_64",
+			kernelVersion: "#1 SMP Tue Jun 27 21:48:55 UTC 2023",
+			expected:      root.Target{Distro: "amazonlinux2", KernelRelease: "5.10.184-175.731.amzn2.x86_64", KernelVersion: "1"},
+		},
+		"centos": {
+			osRelease:     "# comment\nNAME='CentOS Stream'\nID='centos'\nVERSION_ID='9'\n",
+			kernelRelease: "5.14.0-325.el9.x86_64\n",
+			kernelVersion: "#1 SMP PREEMPT_DYNAMIC Thu Jun 8 13:53:16 UTC 2023\n",
+			expected:      root.Target{Distro: "centos", KernelRelease: "5.14.0-325.el9.x86_64", KernelVersion: "1"},
+		},
+		"debian rt": {
+			osRelease:     "ID=debian\nVERSION_ID=\"10\"\n",
+			kernelRelease: "5.10.0-0.deb10.22-rt-amd64",
+			kernelVersion: "#1 SMP PREEMPT_RT Debian 5.10.178-3~deb10u1 (2023-04-22)",
+			expected:      root.Target{Distro: "debian", KernelRelease: "5.10.178-3-rt-amd64", KernelVersion: "1"},
+		},
+		"unsupported distro": {
+			osRelease:     "ID=gentoo\n",
+			kernelRelease: "6.1.31-gentoo",
+			kernelVersion: "#1 SMP PREEMPT_DYNAMIC",
+			expectError:   true,
+		},
+		"missing ID": {
+			osRelease:     "NAME=\"Unknown\"\n",
+			kernelRelease: "6.1.31",
+			kernelVersion: "#1 SMP",
+			expectError:   true,
+		},
+		"empty kernel release": {
+			osRelease:     "ID=centos\n",
+			kernelVersion: "#1 SMP",
+			expectError:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := HostFiles{
+				OSRelease:     filepath.Join(dir, "os-release"),
+				KernelRelease: filepath.Join(dir, "osrelease"),
+				KernelVersion: filepath.Join(dir, "version"),
+			}
+			assert.NoError(t, os.WriteFile(files.OSRelease, []byte(test.osRelease), 0o644))
+			assert.NoError(t, os.WriteFile(files.KernelRelease, []byte(test.kernelRelease), 0o644))
+			assert.NoError(t, os.WriteFile(files.KernelVersion, []byte(test.kernelVersion), 0o644))
+
+			target, err := hostTarget(files)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, target)
+		})
+	}
+}
+
+func TestGenerateHostOptions(t *testing.T) {
+	opts := Options{
+		Options: root.Options{
+			Architecture:  "amd64",
+			DriverVersion: []string{"1.0.0+driver"},
+		},
+		Host: true,
+		Auto: true,
+	}
+	assert.Error(t, Run(context.Background(), opts))
+
+	opts.Auto = false
+	opts.Target.Distro = "centos"
+	assert.Error(t, Run(context.Background(), opts))
+
+	opts.Target.Distro = ""
+	opts.HostFiles = HostFiles{OSRelease: filepath.Join(t.TempDir(), "missing")}
+	assert.Error(t, Run(context.Background(), opts))
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+)
+
+// Retention bounds generated configs to the latest N kernel releases
+// for each target and major.minor kernel version, eg: the latest 3 ubuntu-aws 5.15 kernels.
+// N can be overridden per target; 0 means no limit.
+// There is no time based retention, since kernel-crawler entries carry no publication date.
+type Retention struct {
+	keep     int
+	byTarget map[string]int
+}
+
+// ParseRetention parses a list of "N" (any target) or "<target>=N" values.
+func ParseRetention(values []string) (Retention, error) {
+	r := Retention{byTarget: make(map[string]int)}
+	for _, value := range values {
+		target, count, found := strings.Cut(value, "=")
+		if !found {
+			target, count = "", value
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 0 {
+			return Retention{}, fmt.Errorf("invalid retention %q: expected a non negative number of kernel releases", value)
+		}
+		if target == "" {
+			r.keep = n
+		} else {
+			r.byTarget[target] = n
+		}
+	}
+	return r, nil
+}
+
+func (r Retention) IsSet() bool {
+	return r.keep > 0 || len(r.byTarget) > 0
+}
+
+func (r Retention) keepFor(target string) int {
+	if n, ok := r.byTarget[target]; ok {
+		return n
+	}
+	return r.keep
+}
+
+// apply splits the entries into retained and dropped ones, keeping their order.
+func (r Retention) apply(entries []crawlerEntry) ([]crawlerEntry, []crawlerEntry) {
+	if !r.IsSet() {
+		return entries, nil
+	}
+
+	releasesByGroup := make(map[string][]string)
+	groupOf := func(entry crawlerEntry) string {
+		kr := kernelrelease.FromString(entry.KernelRelease)
+		return fmt.Sprintf("%s/%d.%d", entry.Target, kr.Major, kr.Minor)
+	}
+	for _, entry := range entries {
+		group := groupOf(entry)
+		if !slices.Contains(releasesByGroup[group], entry.KernelRelease) {
+			releasesByGroup[group] = append(releasesByGroup[group], entry.KernelRelease)
+		}
+	}
+
+	retainedReleases := make(map[string]struct{})
+	for group, releases := range releasesByGroup {
+		target, _, _ := strings.Cut(group, "/")
+		keep := r.keepFor(target)
+		if keep > 0 && len(releases) > keep {
+			// Latest first
+			slices.SortFunc(releases, func(a, b string) int {
+				return root.CompareKernelReleases(b, a)
+			})
+			releases = releases[:keep]
+		}
+		for _, release := range releases {
+			retainedReleases[group+"/"+release] = struct{}{}
+		}
+	}
+
+	var retained, dropped []crawlerEntry
+	for _, entry := range entries {
+		if _, ok := retainedReleases[groupOf(entry)+"/"+entry.KernelRelease]; ok {
+			retained = append(retained, entry)
+		} else {
+			dropped = append(dropped, entry)
+		}
+	}
+	return retained, dropped
+}
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetention(t *testing.T) {
+	tests := map[string]struct {
+		values      []string
+		expectError bool
+		expected    Retention
+	}{
+		"unset": {
+			expected: Retention{byTarget: map[string]int{}},
+		},
+		"default and per target": {
+			values:   []string{"3", "ubuntu-aws=5", "centos=0"},
+			expected: Retention{keep: 3, byTarget: map[string]int{"ubuntu-aws": 5, "centos": 0}},
+		},
+		"not a number": {
+			values:      []string{"ubuntu-aws=many"},
+			expectError: true,
+		},
+		"negative": {
+			values:      []string{"-1"},
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			retention, err := ParseRetention(test.values)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, retention)
+		})
+	}
+}
+
+func TestRetentionApply(t *testing.T) {
+	entry := func(target, kernelRelease, kernelVersion string) crawlerEntry {
+		return crawlerEntry{DriverkitYaml: validate.DriverkitYaml{Target: target, KernelRelease: kernelRelease, KernelVersion: kernelVersion}}
+	}
+	entries := []crawlerEntry{
+		entry("ubuntu-aws", "5.15.0-999-aws", "1"),
+		entry("ubuntu-aws", "5.15.0-1040-aws", "47"),
+		entry("ubuntu-aws", "5.15.0-1040-aws", "48"),
+		entry("ubuntu-aws", "5.15.0-1100-aws", "1"),
+		entry("ubuntu-aws", "5.4.0-1020-aws", "1"),
+		entry("ubuntu-aws", "5.4.0-1100-aws", "1"),
+		entry("ubuntu-generic", "5.15.0-76-generic", "1"),
+		entry("ubuntu-generic", "5.15.0-80-generic", "1"),
+	}
+	names := func(entries []crawlerEntry) []string {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.ToName())
+		}
+		return names
+	}
+
+	tests := map[string]struct {
+		values          []string
+		expectedDropped []string
+	}{
+		"no retention": {},
+		"latest release per major.minor": {
+			values: []string{"1"},
+			expectedDropped: []string{
+				"ubuntu-aws_5.15.0-999-aws_1",
+				"ubuntu-aws_5.15.0-1040-aws_47",
+				"ubuntu-aws_5.15.0-1040-aws_48",
+				"ubuntu-aws_5.4.0-1020-aws_1",
+				"ubuntu-generic_5.15.0-76-generic_1",
+			},
+		},
+		"kernel versions of retained releases are all kept": {
+			values:          []string{"2"},
+			expectedDropped: []string{"ubuntu-aws_5.15.0-999-aws_1"},
+		},
+		"per target override": {
+			values:          []string{"1", "ubuntu-aws=0"},
+			expectedDropped: []string{"ubuntu-generic_5.15.0-76-generic_1"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			retention, err := ParseRetention(test.values)
+			assert.NoError(t, err)
+			retained, dropped := retention.apply(entries)
+			assert.Equal(t, test.expectedDropped, names(dropped))
+			assert.Len(t, retained, len(entries)-len(test.expectedDropped))
+		})
+	}
+}
+
+func TestGenerateRetentionSync(t *testing.T) {
+	withoutTestCrawlerData(t)
+
+	crawlerInput := filepath.Join(t.TempDir(), "list.json")
+	crawlerJson := `{"centos": [
+  {"kernelversion": "1", "kernelrelease": "4.18.0-477.el8.x86_64", "target": "centos"},
+  {"kernelversion": "1", "kernelrelease": "4.18.0-500.el8.x86_64", "target": "centos"},
+  {"kernelversion": "1", "kernelrelease": "5.14.0-325.el9.x86_64", "target": "centos"}
+]}`
+	assert.NoError(t, os.WriteFile(crawlerInput, []byte(crawlerJson), 0o644))
+
+	opts := Options{
+		Options: root.Options{
+			RepoRoot:      t.TempDir(),
+			Architecture:  "amd64",
+			DriverVersion: []string{"1.0.0+driver"},
+			DriverName:    "falco",
+			Result:        root.NewResult(),
+		},
+		Auto:          true,
+		CrawlerInputs: []string{crawlerInput},
+	}
+	// Generate everything, then apply a retention policy
+	assert.NoError(t, Run(context.Background(), opts))
+
+	opts.Retention, _ = ParseRetention([]string{"1"})
+	opts.Result = root.NewResult()
+	opts.Sync = true
+	opts.Prune = true
+	assert.NoError(t, Run(context.Background(), opts))
+
+	configs, err := filepath.Glob(root.BuildConfigPath(opts.Options, "1.0.0+driver", "*.yaml"))
+	assert.NoError(t, err)
+	configNames := make([]string, 0, len(configs))
+	for _, config := range configs {
+		configNames = append(configNames, filepath.Base(config))
+	}
+	assert.ElementsMatch(t, []string{"centos_4.18.0-500.el8.x86_64_1.yaml", "centos_5.14.0-325.el9.x86_64_1.yaml"}, configNames)
+}
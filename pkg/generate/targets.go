@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// TargetStatus is the outcome of generating the configs of an explicit target.
+type TargetStatus string
+
+const (
+	TargetStatusOK TargetStatus = "ok"
+	// TargetStatusWarning is used when configs were written, but kernel headers could not be resolved.
+	TargetStatusWarning     TargetStatus = "warning"
+	TargetStatusUnsupported TargetStatus = "unsupported"
+	TargetStatusFailed      TargetStatus = "failed"
+)
+
+type targetsFile struct {
+	Targets []targetsFileEntry `yaml:"targets"`
+}
+
+type targetsFileEntry struct {
+	Distro        string `yaml:"distro"`
+	KernelRelease string `yaml:"kernelrelease"`
+	KernelVersion string `yaml:"kernelversion"`
+}
+
+// LoadTargets loads the explicit targets listed by a targets file.
+// Files with a ".csv" extension are made of distro,kernelrelease,kernelversion records,
+// with an optional header; any other file is a yaml file with a "targets" list.
+// Kernel version defaults to "1" when empty.
+func LoadTargets(path string) ([]root.Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []targetsFileEntry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = parseTargetsCSV(data)
+	} else {
+		var f targetsFile
+		err = yaml.Unmarshal(data, &f)
+		entries = f.Targets
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse targets file %s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no targets found in %s", path)
+	}
+
+	targets := make([]root.Target, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Distro == "" || entry.KernelRelease == "" {
+			return nil, fmt.Errorf("target %d in %s: distro and kernelrelease are required", i+1, path)
+		}
+		if entry.KernelVersion == "" {
+			entry.KernelVersion = "1"
+		}
+		targets = append(targets, root.Target{
+			Distro:        builder.Type(entry.Distro),
+			KernelRelease: entry.KernelRelease,
+			KernelVersion: entry.KernelVersion,
+		})
+	}
+	return targets, nil
+}
+
+func parseTargetsCSV(data []byte) ([]targetsFileEntry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	var entries []targetsFileEntry
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 || len(record) > 3 {
+			line, _ := r.FieldPos(0)
+			return nil, fmt.Errorf("line %d: expected distro,kernelrelease[,kernelversion]", line)
+		}
+		// Skip the header, if any
+		if len(entries) == 0 && strings.EqualFold(record[0], "distro") {
+			continue
+		}
+		entry := targetsFileEntry{Distro: record[0], KernelRelease: record[1]}
+		if len(record) == 3 {
+			entry.KernelVersion = record[2]
+		}
+		entries = append(entries, entry)
+	}
+}
+
+type targetSummary struct {
+	Architecture  kernelrelease.Architecture `json:"architecture" yaml:"architecture"`
+	Distro        builder.Type               `json:"distro" yaml:"distro"`
+	KernelRelease string                     `json:"kernelrelease" yaml:"kernelrelease"`
+	KernelVersion string                     `json:"kernelversion" yaml:"kernelversion"`
+	Status        TargetStatus               `json:"status" yaml:"status"`
+	Message       string                     `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// targetsDetails summarizes, for each architecture, the outcome of each explicit target.
+type targetsDetails struct {
+	Targets []targetSummary `json:"targets" yaml:"targets"`
+}
+
+func (t targetsDetails) Header() []string {
+	return []string{"Architecture", "Target", "Kernel Release", "Kernel Version", "Status", "Message"}
+}
+
+func (t targetsDetails) Rows() [][]string {
+	rows := make([][]string, 0, len(t.Targets))
+	for _, target := range t.Targets {
+		rows = append(rows, []string{
+			target.Architecture.String(),
+			target.Distro.String(),
+			target.KernelRelease,
+			target.KernelVersion,
+			string(target.Status),
+			target.Message,
+		})
+	}
+	return rows
+}
+
+// count returns the number of targets with the given status.
+func (t targetsDetails) count(status TargetStatus) int {
+	n := 0
+	for _, target := range t.Targets {
+		if target.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// generateTargetsConfigs generates the configs of each explicit target, resolving
+// their kernel headers through driverkit, with up to opts.ParallelJobs() targets in parallel.
+// Unsupported targets and unresolved kernel headers don't stop the loop, and are reported in the summary.
+func generateTargetsConfigs(ctx context.Context, opts Options) ([]targetSummary, error) {
+	summaries := make([]targetSummary, len(opts.Targets))
+	errGrp, errGrpCtx := errgroup.WithContext(ctx)
+	errGrp.SetLimit(opts.ParallelJobs())
+	for i, target := range opts.Targets {
+		summaries[i] = targetSummary{
+			Architecture:  opts.Architecture,
+			Distro:        target.Distro,
+			KernelRelease: target.KernelRelease,
+			KernelVersion: target.KernelVersion,
+		}
+		errGrp.Go(func() error {
+			summary := &summaries[i]
+			if err := errGrpCtx.Err(); err != nil {
+				summary.Status, summary.Message = TargetStatusFailed, err.Error()
+				return err
+			}
+			targetOpts := opts
+			targetOpts.Target = root.Target{
+				Distro:        target.Distro,
+				KernelRelease: target.KernelRelease,
+				KernelVersion: target.KernelVersion,
+			}
+			warning, err := generateTargetConfig(targetOpts)
+			var unsupportedTargetError *UnsupportedTargetErr
+			switch {
+			case errors.As(err, &unsupportedTargetError):
+				root.Printer.Logger.Warn("skipping unsupported target",
+					root.Printer.Logger.Args("target", target.Distro, "kernelrelease", target.KernelRelease))
+				summary.Status, summary.Message = TargetStatusUnsupported, err.Error()
+				dkYaml := validate.DriverkitYaml{
+					Target:        target.Distro.String(),
+					KernelRelease: target.KernelRelease,
+					KernelVersion: target.KernelVersion,
+				}
+				for _, driverVersion := range opts.DriverVersion {
+					opts.Result.Record(root.ResultItem{
+						Action:        actionGenerating,
+						Architecture:  opts.Architecture,
+						DriverVersion: driverVersion,
+						Path:          root.BuildConfigPath(targetOpts.Options, driverVersion, dkYaml.ToConfigName()),
+					}, err)
+				}
+				return nil
+			case err != nil:
+				summary.Status, summary.Message = TargetStatusFailed, err.Error()
+				return err
+			case warning != nil:
+				summary.Status, summary.Message = TargetStatusWarning, warning.Error()
+			default:
+				summary.Status = TargetStatusOK
+			}
+			return nil
+		})
+	}
+	return summaries, errGrp.Wait()
+}
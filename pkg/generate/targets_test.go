@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package generate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTargets(t *testing.T) {
+	tests := map[string]struct {
+		fileName    string
+		data        string
+		expectError bool
+		expected    []root.Target
+	}{
+		"yaml": {
+			fileName: "targets.yaml",
+			data: `targets:
+  - distro: centos
+    kernelrelease: 5.14.0-325.el9.x86_64
+    kernelversion: "1"
+  - distro: ubuntu-aws
+    kernelrelease: 5.15.0-1040-aws
+`,
+			expected: []root.Target{
+				{Distro: "centos", KernelRelease: "5.14.0-325.el9.x86_64", KernelVersion: "1"},
+				{Distro: "ubuntu-aws", KernelRelease: "5.15.0-1040-aws", KernelVersion: "1"},
+			},
+		},
+		"csv with header and comments": {
+			fileName: "targets.csv",
+			data: `distro,kernelrelease,kernelversion
+# internal kernels
+centos,5.14.0-325.el9.x86_64,1
+ubuntu-aws, 5.15.0-1040-aws
+`,
+			expected: []root.Target{
+				{Distro: "centos", KernelRelease: "5.14.0-325.el9.x86_64", KernelVersion: "1"},
+				{Distro: "ubuntu-aws", KernelRelease: "5.15.0-1040-aws", KernelVersion: "1"},
+			},
+		},
+		"csv with wrong record": {
+			fileName:    "targets.csv",
+			data:        "centos\n",
+			expectError: true,
+		},
+		"missing kernel release": {
+			fileName:    "targets.yaml",
+			data:        "targets:\n  - distro: centos\n",
+			expectError: true,
+		},
+		"no targets": {
+			fileName:    "targets.yaml",
+			data:        "targets: []\n",
+			expectError: true,
+		},
+		"invalid yaml": {
+			fileName:    "targets.yaml",
+			data:        "targets: {\n",
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.fileName)
+			assert.NoError(t, os.WriteFile(path, []byte(test.data), 0o644))
+			targets, err := LoadTargets(path)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, targets)
+		})
+	}
+}
+
+func TestGenerateTargets(t *testing.T) {
+	loadKernelHeadersOrig := loadKernelHeaders
+	t.Cleanup(func() {
+		loadKernelHeaders = loadKernelHeadersOrig
+	})
+	loadKernelHeaders = func(opts Options) ([]string, error) {
+		switch opts.Distro {
+		case "centos":
+			return []string{"https://mirror.example.com/kernel-devel-" + opts.KernelRelease + ".rpm"}, nil
+		case "fedora":
+			return nil, fmt.Errorf("not enough headers packages found; expected 1, found 0")
+		default:
+			return nil, &UnsupportedTargetErr{target: opts.Distro}
+		}
+	}
+
+	opts := Options{
+		Options: root.Options{
+			RepoRoot:      t.TempDir(),
+			Architecture:  "amd64",
+			Architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+			DriverVersion: []string{"1.0.0+driver"},
+			DriverName:    "falco",
+			Jobs:          4,
+			Result:        root.NewResult(),
+		},
+		Targets: []root.Target{
+			{Distro: "centos", KernelRelease: "5.14.0-325.el9.x86_64", KernelVersion: "1"},
+			{Distro: "fedora", KernelRelease: "6.2.9-300.fc38.x86_64", KernelVersion: "1"},
+			{Distro: "gentoo", KernelRelease: "6.1.31-gentoo", KernelVersion: "1"},
+		},
+	}
+	err := Run(context.Background(), opts)
+	assert.Error(t, err)
+
+	details, ok := opts.Result.Details().(targetsDetails)
+	assert.True(t, ok)
+	assert.Len(t, details.Targets, 6)
+	for _, arch := range opts.Archs() {
+		statuses := make(map[string]TargetStatus)
+		for _, target := range details.Targets {
+			if target.Architecture == arch {
+				statuses[target.Distro.String()] = target.Status
+			}
+		}
+		assert.Equal(t, map[string]TargetStatus{
+			"centos": TargetStatusOK,
+			"fedora": TargetStatusWarning,
+			"gentoo": TargetStatusUnsupported,
+		}, statuses)
+
+		configsDir := root.BuildConfigPath(opts.ForArchitecture(arch), "1.0.0+driver", "")
+		_, err = os.Stat(filepath.Join(configsDir, "centos_5.14.0-325.el9.x86_64_1.yaml"))
+		assert.NoError(t, err)
+		_, err = os.Stat(filepath.Join(configsDir, "fedora_6.2.9-300.fc38.x86_64_1.yaml"))
+		assert.NoError(t, err)
+		_, err = os.Stat(filepath.Join(configsDir, "gentoo_6.1.31-gentoo_1.yaml"))
+		assert.True(t, os.IsNotExist(err))
+	}
+
+	report := root.NewReport("generate", opts.Options, err)
+	assert.Equal(t, 4, report.Summary[root.OutcomeSucceeded])
+	assert.Equal(t, 2, report.Summary[root.OutcomeFailed])
+}
+
+func TestGenerateTargetsOptions(t *testing.T) {
+	opts := Options{
+		Options: root.Options{
+			Architecture:  "amd64",
+			DriverVersion: []string{"1.0.0+driver"},
+		},
+		Auto:    true,
+		Targets: []root.Target{{Distro: "centos", KernelRelease: "5.14.0-325.el9.x86_64", KernelVersion: "1"}},
+	}
+	assert.Error(t, Run(context.Background(), opts))
+
+	opts.Auto = false
+	opts.Target.Distro = "centos"
+	assert.Error(t, Run(context.Background(), opts))
+}
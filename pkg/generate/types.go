@@ -16,9 +16,32 @@ package generate
 
 import (
 	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
 )
 
 type Options struct {
 	root.Options
 	Auto bool
+	// CrawlerInputs are kernel-crawler json urls or local files ("-" means stdin) to be used in auto mode,
+	// in precedence order (last wins); by default, DefaultCrawlerSource is used.
+	CrawlerInputs []string
+	// CrawlerCacheDir caches kernel-crawler downloads; empty disables caching.
+	CrawlerCacheDir string
+	// CrawlerRefresh forces a full download, ignoring any cached copy.
+	CrawlerRefresh bool
+	// Sync also reports configs, in the scope of the target filters, that are stale,
+	// ie: whose kernel is no longer listed by kernel-crawler.
+	Sync bool
+	// Prune removes stale configs; only allowed in Sync mode.
+	Prune bool
+	// Overlays are applied on top of each generated config.
+	Overlays validate.Overlays
+	// Retention bounds the kernels configs are generated for, in auto mode.
+	Retention Retention
+	// Host generates a single config for the host described by HostFiles.
+	Host      bool
+	HostFiles HostFiles
+	// Targets are explicit targets, eg: loaded from a targets file, each generating configs
+	// with kernel headers resolved through driverkit.
+	Targets []root.Target
 }
@@ -16,14 +16,17 @@ package generate
 
 import (
 	"io"
-	"net/http"
+	"os"
+	"strings"
+
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
-func getURL(url string) ([]byte, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
+// openCrawlerInput opens a local kernel-crawler json for the given architecture.
+// The caller must close the returned reader.
+func openCrawlerInput(input string, arch kernelrelease.Architecture) (io.ReadCloser, error) {
+	if input == CrawlerInputStdin {
+		return io.NopCloser(os.Stdin), nil
 	}
-	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
+	return os.Open(strings.ReplaceAll(input, CrawlerInputArchPlaceholder, arch.ToNonDeb()))
 }
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promote
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"gopkg.in/yaml.v3"
+)
+
+var errUnbuildable = fmt.Errorf("%w: kernel supports neither module nor probe", root.ErrSkipped)
+
+func Run(ctx context.Context, opts Options) error {
+	root.Printer.Logger.Info("promoting config files",
+		root.Printer.Logger.Args("from", opts.From, "to", opts.To))
+	if opts.From == "" || opts.To == "" {
+		return fmt.Errorf("both source and destination driver versions must be passed")
+	}
+	if opts.From == opts.To {
+		return fmt.Errorf("source and destination driver versions must differ, got %s", opts.From)
+	}
+
+	fromOpts := opts.Options
+	fromOpts.DriverVersion = []string{opts.From}
+	configs, err := root.ListFiltered(root.BuildConfigPath, fromOpts, "promoting")
+	if err != nil {
+		return err
+	}
+	for _, config := range configs {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		archOpts := opts
+		archOpts.Options = opts.ForArchitecture(config.Architecture)
+		if err = promoteConfig(archOpts, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func promoteConfig(opts Options, config root.ResultItem) error {
+	entry := root.ResultItem{
+		Action:        config.Action,
+		Architecture:  opts.Architecture,
+		DriverVersion: opts.To,
+		Path:          config.Path,
+		Source:        config.Path,
+	}
+	yamlData, dkYaml, err := loadConfig(config.Path)
+	if err != nil {
+		opts.Result.Record(entry, err)
+		return err
+	}
+	entry.Path = root.BuildConfigPath(opts.Options, opts.To, dkYaml.ToConfigName())
+
+	kr := kernelrelease.FromString(dkYaml.KernelRelease)
+	kr.Architecture = opts.Architecture
+	if opts.DropUnbuildable && !kr.SupportsModule() && !kr.SupportsProbe() {
+		root.Printer.Logger.Info("dropping kernel supporting neither module nor probe",
+			root.Printer.Logger.Args("config", config.Path))
+		opts.Result.Record(entry, errUnbuildable)
+		return nil
+	}
+
+	// Outputs are fully recomputed for the new driver version
+	dkYaml.Architecture = opts.Architecture.String()
+	dkYaml.Output = validate.DriverkitYamlOutputs{}
+	dkYaml.FillOutputs(opts.To, opts.Options)
+	opts.Overlays.Apply(&dkYaml)
+	newData, err := yaml.Marshal(&dkYaml)
+	if err != nil {
+		opts.Result.Record(entry, err)
+		return err
+	}
+	// Keep leading comments, eg: notes about the kernel
+	newData = append(validate.LeadingComments(yamlData), newData...)
+
+	action, err := root.WriteAction(entry.Path, newData)
+	if err != nil {
+		opts.Result.Record(entry, err)
+		return err
+	}
+	if action == "" {
+		opts.Result.Record(entry, root.ErrUnchanged)
+		return nil
+	}
+	entry.Action = action
+	if opts.DryRun {
+		root.Printer.Logger.Info("skipping because of dry-run.",
+			root.Printer.Logger.Args("config", entry.Path))
+		opts.Result.Plan(entry)
+		return nil
+	}
+
+	root.Printer.Logger.Info("promoting",
+		root.Printer.Logger.Args("config", config.Path, "to", entry.Path))
+	err = os.MkdirAll(filepath.Dir(entry.Path), os.ModePerm)
+	if err == nil {
+		err = root.WriteFileAtomic(entry.Path, newData, 0o644)
+	}
+	opts.Result.Record(entry, err)
+	return err
+}
+
+func loadConfig(configPath string) ([]byte, validate.DriverkitYaml, error) {
+	var dkYaml validate.DriverkitYaml
+	yamlData, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, dkYaml, err
+	}
+	if err = yaml.Unmarshal(yamlData, &dkYaml); err != nil {
+		return nil, dkYaml, fmt.Errorf("config %s: %w", configPath, err)
+	}
+	return yamlData, dkYaml, nil
+}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	fromVersion = "5.0.1+driver"
+	toVersion   = "6.0.0+driver"
+)
+
+var testConfigs = []validate.DriverkitYaml{
+	{KernelVersion: "1", KernelRelease: "5.14.0-325.el9.x86_64", Target: "centos", KernelUrls: []string{"http://centos/kernel-devel.rpm"}},
+	{KernelVersion: "1", KernelRelease: "4.18.0-477.el8.x86_64", Target: "centos", KernelUrls: []string{"http://centos/kernel-devel-el8.rpm"}},
+	{KernelVersion: "26", KernelRelease: "4.19.0-26-amd64", Target: "debian", KernelUrls: []string{"http://debian/linux-headers.deb"}},
+	{KernelVersion: "1", KernelRelease: "3.10.0-1160.el7.aarch64", Target: "centos", KernelUrls: []string{"http://centos/kernel-devel-el7.rpm"}},
+}
+
+// writeTestConfigs writes the test configs for the source driver version,
+// as generated on the architecture they belong to.
+func writeTestConfigs(t *testing.T, opts root.Options) {
+	for _, dkYaml := range testConfigs {
+		arch := kernelrelease.Architecture("amd64")
+		if strings.HasSuffix(dkYaml.KernelRelease, "aarch64") {
+			arch = "arm64"
+		}
+		archOpts := opts.ForArchitecture(arch)
+		dkYaml.Architecture = arch.String()
+		dkYaml.FillOutputs(fromVersion, archOpts)
+		data, err := yaml.Marshal(&dkYaml)
+		assert.NoError(t, err)
+		data = append([]byte("# Hand written note\n"), data...)
+		configPath := root.BuildConfigPath(archOpts, fromVersion, dkYaml.ToConfigName())
+		assert.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0o755))
+		assert.NoError(t, os.WriteFile(configPath, data, 0o644))
+	}
+}
+
+func TestPromote(t *testing.T) {
+	tests := map[string]struct {
+		target          root.Target
+		dropUnbuildable bool
+		dryRun          bool
+		expectedOutcome map[string]root.Outcome
+	}{
+		"promote all configs": {
+			expectedOutcome: map[string]root.Outcome{
+				"centos_5.14.0-325.el9.x86_64_1.yaml":   root.OutcomeSucceeded,
+				"centos_4.18.0-477.el8.x86_64_1.yaml":   root.OutcomeSucceeded,
+				"debian_4.19.0-26-amd64_26.yaml":        root.OutcomeSucceeded,
+				"centos_3.10.0-1160.el7.aarch64_1.yaml": root.OutcomeSucceeded,
+			},
+		},
+		"promote filtered configs": {
+			target: root.Target{
+				Distro:               "centos",
+				ExcludeKernelRelease: []string{`^4\.`},
+			},
+			expectedOutcome: map[string]root.Outcome{
+				"centos_5.14.0-325.el9.x86_64_1.yaml":   root.OutcomeSucceeded,
+				"centos_3.10.0-1160.el7.aarch64_1.yaml": root.OutcomeSucceeded,
+			},
+		},
+		"promote dropping unbuildable kernels": {
+			dropUnbuildable: true,
+			expectedOutcome: map[string]root.Outcome{
+				"centos_5.14.0-325.el9.x86_64_1.yaml":   root.OutcomeSucceeded,
+				"centos_4.18.0-477.el8.x86_64_1.yaml":   root.OutcomeSucceeded,
+				"debian_4.19.0-26-amd64_26.yaml":        root.OutcomeSucceeded,
+				"centos_3.10.0-1160.el7.aarch64_1.yaml": root.OutcomeSkipped,
+			},
+		},
+		"promote in dry-run mode": {
+			dryRun: true,
+			target: root.Target{Distro: "debian"},
+			expectedOutcome: map[string]root.Outcome{
+				"debian_4.19.0-26-amd64_26.yaml": root.OutcomePlanned,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{
+				Options: root.Options{
+					RepoRoot:      t.TempDir(),
+					Architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+					DriverName:    "falco",
+					DryRun:        test.dryRun,
+					Result:        root.NewResult(),
+					Target:        test.target,
+				},
+				From:            fromVersion,
+				To:              toVersion,
+				DropUnbuildable: test.dropUnbuildable,
+			}
+			writeTestConfigs(t, opts.Options)
+
+			assert.NoError(t, Run(context.Background(), opts))
+			outcomes := make(map[string]root.Outcome)
+			for _, item := range opts.Result.Items() {
+				assert.Equal(t, toVersion, item.DriverVersion)
+				outcomes[filepath.Base(item.Path)] = item.Outcome
+			}
+			assert.Equal(t, test.expectedOutcome, outcomes)
+
+			// Promoted configs must be valid for the new driver version
+			for _, item := range opts.Result.Items() {
+				_, err := os.Stat(item.Path)
+				if item.Outcome != root.OutcomeSucceeded {
+					assert.ErrorIs(t, err, os.ErrNotExist)
+					continue
+				}
+				assert.NoError(t, err)
+				data, err := os.ReadFile(item.Path)
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(string(data), "# Hand written note\n"))
+				assert.NotContains(t, string(data), fromVersion)
+				var dkYaml validate.DriverkitYaml
+				assert.NoError(t, yaml.Unmarshal(data, &dkYaml))
+				if dkYaml.Output.Module != "" {
+					assert.Contains(t, dkYaml.Output.Module, toVersion)
+				}
+			}
+			if !test.dryRun {
+				validateOpts := validate.Options{Options: opts.Options}
+				validateOpts.DriverVersion = []string{toVersion}
+				validateOpts.Result = root.NewResult()
+				assert.NoError(t, validate.Run(context.Background(), validateOpts))
+			}
+
+			// Promoting again is a no-op
+			opts.Result = root.NewResult()
+			assert.NoError(t, Run(context.Background(), opts))
+			for _, item := range opts.Result.Items() {
+				if test.dryRun {
+					assert.Equal(t, root.OutcomePlanned, item.Outcome)
+				} else {
+					assert.Equal(t, root.OutcomeSkipped, item.Outcome)
+				}
+			}
+		})
+	}
+}
+
+func TestPromoteOverlays(t *testing.T) {
+	overlays, err := validate.ParseOverlays([]byte("overlays:\n  - distro: ^centos$\n    kernelrelease: ^5\\.\n    disableprobe: true\n"))
+	assert.NoError(t, err)
+	opts := Options{
+		Options: root.Options{
+			RepoRoot:      t.TempDir(),
+			Architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+			DriverName:    "falco",
+			Result:        root.NewResult(),
+		},
+		From:     fromVersion,
+		To:       toVersion,
+		Overlays: overlays,
+	}
+	writeTestConfigs(t, opts.Options)
+	assert.NoError(t, Run(context.Background(), opts))
+
+	for _, item := range opts.Result.Items() {
+		assert.Equal(t, root.OutcomeSucceeded, item.Outcome)
+		data, err := os.ReadFile(item.Path)
+		assert.NoError(t, err)
+		var dkYaml validate.DriverkitYaml
+		assert.NoError(t, yaml.Unmarshal(data, &dkYaml))
+		if filepath.Base(item.Path) == "centos_5.14.0-325.el9.x86_64_1.yaml" {
+			assert.Empty(t, dkYaml.Output.Probe)
+		} else if dkYaml.Target == "debian" {
+			assert.NotEmpty(t, dkYaml.Output.Probe)
+		}
+	}
+
+	// Promoted configs do not drift from overlays
+	validateOpts := validate.Options{Options: opts.Options, Overlays: overlays}
+	validateOpts.DriverVersion = []string{toVersion}
+	validateOpts.Result = root.NewResult()
+	assert.NoError(t, validate.Run(context.Background(), validateOpts))
+}
+
+func TestPromoteOptions(t *testing.T) {
+	opts := Options{
+		Options: root.Options{Architecture: "amd64", RepoRoot: t.TempDir()},
+		From:    fromVersion,
+	}
+	assert.Error(t, Run(context.Background(), opts))
+
+	opts.To = fromVersion
+	assert.Error(t, Run(context.Background(), opts))
+}
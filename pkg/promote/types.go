@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package promote
+
+import (
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/dbg-go/pkg/validate"
+)
+
+type Options struct {
+	root.Options
+	// From is the driver version whose configs are promoted.
+	From string
+	// To is the driver version configs are promoted to.
+	To string
+	// DropUnbuildable skips configs for kernels supporting neither the kernel module nor the probe.
+	// It only depends on the kernel release, not on the driver version configs are promoted to.
+	DropUnbuildable bool
+	// Overlays are applied on top of each promoted config, as for generated ones.
+	Overlays validate.Overlays
+}
@@ -15,14 +15,17 @@ limitations under the License.
 package publish
 
 import (
+	"context"
+
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	s3utils "github.com/falcosecurity/dbg-go/pkg/utils/s3"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 // Used by tests
 var testClient *s3utils.Client
 
-func Run(opts Options) error {
+func Run(ctx context.Context, opts Options) error {
 	root.Printer.Logger.Info("publishing drivers")
 	var (
 		client *s3utils.Client
@@ -37,7 +40,7 @@ func Run(opts Options) error {
 		client = testClient
 	}
 	looper := root.NewFsLooper(root.BuildOutputPath)
-	return looper.LoopFiltered(opts.Options, "publishing", "driver", func(driverVersion, path string) error {
-		return client.PutDriver(opts.Options, driverVersion, path)
+	return looper.LoopFiltered(ctx, opts.Options, "publishing", "driver", func(ctx context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+		return client.PutDriver(ctx, opts.ForArchitecture(arch), driverVersion, path)
 	})
 }
@@ -56,7 +56,7 @@ func TestPublish(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Run our action to upload our driver object
-	err = Run(Options{
+	err = Run(context.Background(), Options{
 		Options: root.Options{
 			RepoRoot:      "./test",
 			Architecture:  "amd64",
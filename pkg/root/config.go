@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConfigFileName = "dbg-go.yaml"
+	profilesKey    = "profiles"
+)
+
+// FindConfigFile returns the path of the project configuration file to be loaded.
+// An explicitly passed path always wins; otherwise, the file is searched for
+// in the repo root, then in the user config dir (ie: $XDG_CONFIG_HOME/dbg-go/).
+// An empty path is returned when no configuration file is found.
+func FindConfigFile(explicitPath, repoRoot string) (string, error) {
+	if explicitPath != "" {
+		if _, err := os.Stat(explicitPath); err != nil {
+			return "", err
+		}
+		return explicitPath, nil
+	}
+
+	candidates := []string{filepath.Join(repoRoot, ConfigFileName)}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(configDir, "dbg-go", ConfigFileName))
+	}
+	for _, candidate := range candidates {
+		_, err := os.Stat(candidate)
+		if err == nil {
+			return candidate, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// LoadConfigFile merges the project configuration file values into v, as config values;
+// therefore, explicitly set flags still override them.
+// The configuration file is made of flag values, optionally grouped by command path
+// (eg: a "configs: build:" section only applies to the configs build command, and a "s3:" one to every s3 command),
+// and of named profiles, each one with the same layout, that are applied on top of the top level values:
+//
+//	architecture: amd64
+//	configs:
+//	  build:
+//	    skip-existing: true
+//	profiles:
+//	  nightly-arm64:
+//	    architecture: arm64
+//	    configs:
+//	      build:
+//	        publish: true
+//
+// commandPath is the path of the running command, without the root one, eg: ["configs", "build"].
+func LoadConfigFile(v *viper.Viper, path, profile string, commandPath []string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var cfg map[string]any
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	settings := make(map[string]any)
+	mergeConfigSection(settings, cfg, commandPath)
+	if profile != "" {
+		profiles, _ := cfg[profilesKey].(map[string]any)
+		profileCfg, ok := profiles[profile].(map[string]any)
+		if !ok {
+			return fmt.Errorf("profile %s not found in config file %s", profile, path)
+		}
+		mergeConfigSection(settings, profileCfg, commandPath)
+	}
+	Printer.Logger.Debug("loaded config file",
+		Printer.Logger.Args("path", path, "profile", profile, "settings", settings))
+	return v.MergeConfigMap(settings)
+}
+
+// mergeConfigSection stores section values into settings, then the values of each nested
+// command path section; values from the most specific section take precedence.
+func mergeConfigSection(settings, section map[string]any, commandPath []string) {
+	for key, val := range section {
+		if _, isSection := val.(map[string]any); isSection {
+			continue
+		}
+		settings[key] = val
+	}
+	if len(commandPath) == 0 || commandPath[0] == profilesKey {
+		return
+	}
+	if commandSection, ok := section[commandPath[0]].(map[string]any); ok {
+		mergeConfigSection(settings, commandSection, commandPath[1:])
+	}
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfigFile = `
+architecture: amd64
+driver-version:
+  - 5.0.1+driver
+configs:
+  build:
+    skip-existing: false
+s3:
+  cleanup:
+    publish: true
+profiles:
+  nightly-arm64:
+    architecture: arm64
+    target-distro: ubuntu
+    configs:
+      build:
+        publish: true
+  release:
+    driver-version:
+      - 6.0.0+driver
+      - 6.0.1+driver
+`
+
+func TestLoadConfigFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ConfigFileName)
+	err := os.WriteFile(configPath, []byte(testConfigFile), 0644)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		profile     string
+		command     []string
+		args        []string
+		expectError bool
+		expected    map[string]any
+	}{
+		"top level values": {
+			command: []string{"configs", "validate"},
+			expected: map[string]any{
+				"architecture":   "amd64",
+				"driver-version": []string{"5.0.1+driver"},
+				"skip-existing":  true, // flag default, build section not applied
+				"publish":        false,
+			},
+		},
+		"top level values with command section": {
+			command: []string{"configs", "build"},
+			expected: map[string]any{
+				"architecture":  "amd64",
+				"skip-existing": false,
+				"publish":       false,
+			},
+		},
+		"profile values with command section": {
+			profile: "nightly-arm64",
+			command: []string{"configs", "build"},
+			expected: map[string]any{
+				"architecture":   "arm64",
+				"target-distro":  "ubuntu",
+				"driver-version": []string{"5.0.1+driver"},
+				"skip-existing":  false,
+				"publish":        true,
+			},
+		},
+		"profile values overridden by flags": {
+			profile: "release",
+			command: []string{"configs", "build"},
+			args:    []string{"--architecture", "amd64", "--driver-version", "7.0.0+driver", "--publish"},
+			expected: map[string]any{
+				"architecture":   "amd64",
+				"driver-version": []string{"7.0.0+driver"},
+				"publish":        true,
+			},
+		},
+		"profile overriding top level values": {
+			profile: "release",
+			command: []string{"configs", "stats"},
+			expected: map[string]any{
+				"driver-version": []string{"6.0.0+driver", "6.0.1+driver"},
+			},
+		},
+		"same leaf command, different path": {
+			command: []string{"configs", "cleanup"},
+			expected: map[string]any{
+				"skip-existing": true,
+				"publish":       false, // s3 cleanup section not applied
+			},
+		},
+		"nested command section": {
+			command: []string{"s3", "cleanup"},
+			expected: map[string]any{
+				"architecture": "amd64",
+				"publish":      true,
+			},
+		},
+		"missing profile": {
+			profile:     "WRONG_PROFILE",
+			command:     []string{"configs", "build"},
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("architecture", "amd64", "")
+			flags.String("target-distro", "", "")
+			flags.StringSlice("driver-version", nil, "")
+			flags.Bool("skip-existing", true, "")
+			flags.Bool("publish", false, "")
+			assert.NoError(t, flags.Parse(test.args))
+
+			v := viper.New()
+			assert.NoError(t, v.BindPFlags(flags))
+			err := LoadConfigFile(v, configPath, test.profile, test.command)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for key, expected := range test.expected {
+				switch expectedVal := expected.(type) {
+				case []string:
+					assert.Equal(t, expectedVal, v.GetStringSlice(key), key)
+				case bool:
+					assert.Equal(t, expectedVal, v.GetBool(key), key)
+				default:
+					assert.Equal(t, expectedVal, v.GetString(key), key)
+				}
+			}
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	repoRoot := t.TempDir()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	// No config file anywhere
+	path, err := FindConfigFile("", repoRoot)
+	assert.NoError(t, err)
+	assert.Empty(t, path)
+
+	// Config file in user config dir
+	userConfig := filepath.Join(configHome, "dbg-go", ConfigFileName)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(userConfig), 0700))
+	assert.NoError(t, os.WriteFile(userConfig, nil, 0644))
+	path, err = FindConfigFile("", repoRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, userConfig, path)
+
+	// Config file in repo root takes precedence
+	repoConfig := filepath.Join(repoRoot, ConfigFileName)
+	assert.NoError(t, os.WriteFile(repoConfig, nil, 0644))
+	path, err = FindConfigFile("", repoRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, repoConfig, path)
+
+	// Explicit config file always wins, and must exist
+	path, err = FindConfigFile(userConfig, repoRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, userConfig, path)
+	_, err = FindConfigFile(filepath.Join(repoRoot, "missing.yaml"), repoRoot)
+	assert.Error(t, err)
+}
@@ -15,6 +15,12 @@ limitations under the License.
 package root
 
 const (
+	AllArchitectures = "all"
+
+	// Actions reported by WriteAction.
+	ActionCreating = "creating"
+	ActionUpdating = "updating"
+
 	configPathFmt = "%s/driverkit/config/%s/%s/%s" // Eg: repo-root/driverkit/config/5.0.1+driver/x86_64/centos_5.14.0-325.el9.x86_64_1.yaml
 	outputPathFmt = "%s/driverkit/output/%s/%s/%s" // Eg: repo-root/driverkit/output/5.0.1+driver/x86_64/falco_centos_5.14.0-325.el9.x86_64_1.{ko,o}
 )
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+const (
+	LatestDriverVersion = "latest"
+
+	// DriverVersionSourceAnnotation is the command annotation telling where
+	// driver version selectors are resolved; by default, the local config tree is used.
+	DriverVersionSourceAnnotation = "driver-version-source"
+	DriverVersionSourceS3         = "s3"
+	// DriverVersionSourceNone is used by commands not working on driver versions at all.
+	DriverVersionSourceNone = "none"
+)
+
+// CompareDriverVersions compares driver versions by semver precedence;
+// since build metadata is ignored by semver, "+driver" suffixed versions only differing in it
+// are then compared lexicographically. Invalid semver versions sort after valid ones.
+func CompareDriverVersions(a, b string) int {
+	aVer, aErr := semver.Parse(a)
+	bVer, bErr := semver.Parse(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		if c := aVer.Compare(bVer); c != 0 {
+			return c
+		}
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// SortDriverVersions sorts driver versions in place, see CompareDriverVersions.
+func SortDriverVersions(versions []string) {
+	slices.SortFunc(versions, CompareDriverVersions)
+}
+
+// IsDriverVersionSelector returns whether the requested driver version
+// is a selector, ie: "latest", "latest:N" or a semver constraint like ">=6.0.0",
+// instead of an exact driver version.
+func IsDriverVersionSelector(requested string) bool {
+	if _, err := semver.Parse(requested); err == nil {
+		return false
+	}
+	if _, isLatest, _ := parseLatest(requested); isLatest {
+		return true
+	}
+	_, err := semver.ParseRange(requested)
+	return err == nil
+}
+
+// parseLatest parses "latest" and "latest:N" selectors, returning N (1 for "latest")
+// and whether the requested driver version is a latest selector at all.
+func parseLatest(requested string) (int, bool, error) {
+	if requested == LatestDriverVersion {
+		return 1, true, nil
+	}
+	count, found := strings.CutPrefix(requested, LatestDriverVersion+":")
+	if !found {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return 0, true, fmt.Errorf("invalid %s selector %s: count must be a positive integer", LatestDriverVersion, requested)
+	}
+	return n, true, nil
+}
+
+// ResolveDriverVersions expands the requested driver versions against the available ones.
+// Exact versions are kept as they are, even if not available (eg: when generating configs for a new driver version);
+// selectors are replaced by the matching available versions, and must match at least one.
+// An empty request selects all the available versions.
+// Resolved versions are returned sorted and deduplicated.
+func ResolveDriverVersions(requested, available []string) ([]string, error) {
+	available = slices.Clone(available)
+	SortDriverVersions(available)
+	if len(requested) == 0 {
+		if len(available) == 0 {
+			return nil, fmt.Errorf("no driver versions found")
+		}
+		return available, nil
+	}
+
+	// Only valid semver versions can be matched by selectors
+	semverAvailable := make([]string, 0, len(available))
+	for _, version := range available {
+		if _, err := semver.Parse(version); err == nil {
+			semverAvailable = append(semverAvailable, version)
+		}
+	}
+
+	resolved := make([]string, 0, len(requested))
+	for _, req := range requested {
+		if !IsDriverVersionSelector(req) {
+			resolved = append(resolved, req)
+			continue
+		}
+		var matching []string
+		if n, isLatest, err := parseLatest(req); isLatest {
+			if err != nil {
+				return nil, err
+			}
+			matching = semverAvailable[max(0, len(semverAvailable)-n):]
+		} else {
+			versionRange, err := semver.ParseRange(req)
+			if err != nil {
+				return nil, err
+			}
+			for _, version := range semverAvailable {
+				if versionRange(semver.MustParse(version)) {
+					matching = append(matching, version)
+				}
+			}
+		}
+		if len(matching) == 0 {
+			return nil, fmt.Errorf("no available driver version matches %q", req)
+		}
+		resolved = append(resolved, matching...)
+	}
+	SortDriverVersions(resolved)
+	return slices.Compact(resolved), nil
+}
+
+// LocalDriverVersions returns the driver versions available in the local config tree.
+func LocalDriverVersions(repoRoot string) ([]string, error) {
+	configPath := repoRoot + "/driverkit/config/"
+	entries, err := os.ReadDir(configPath)
+	if err != nil {
+		return nil, err
+	}
+	driverVersions := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			driverVersions = append(driverVersions, e.Name())
+		}
+	}
+	return driverVersions, nil
+}
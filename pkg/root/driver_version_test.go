@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortDriverVersions(t *testing.T) {
+	versions := []string{"10.0.0+driver", "2.0.0+driver", "master", "5.0.1+driver", "2.0.0-rc1+driver", "5.0.1+driver2"}
+	SortDriverVersions(versions)
+	assert.Equal(t, []string{"2.0.0-rc1+driver", "2.0.0+driver", "5.0.1+driver", "5.0.1+driver2", "10.0.0+driver", "master"}, versions)
+}
+
+func TestResolveDriverVersions(t *testing.T) {
+	available := []string{"10.0.0+driver", "2.0.0+driver", "6.0.1+driver", "5.0.1+driver", "6.0.0+driver", "master"}
+
+	tests := map[string]struct {
+		requested   []string
+		expected    []string
+		expectError bool
+	}{
+		"no request selects all": {
+			requested: nil,
+			expected:  []string{"2.0.0+driver", "5.0.1+driver", "6.0.0+driver", "6.0.1+driver", "10.0.0+driver", "master"},
+		},
+		"exact versions are kept": {
+			requested: []string{"11.0.0+driver", "5.0.1+driver"},
+			expected:  []string{"5.0.1+driver", "11.0.0+driver"},
+		},
+		"latest": {
+			requested: []string{LatestDriverVersion},
+			expected:  []string{"10.0.0+driver"},
+		},
+		"latest N": {
+			requested: []string{"latest:3"},
+			expected:  []string{"6.0.0+driver", "6.0.1+driver", "10.0.0+driver"},
+		},
+		"latest N greater than available": {
+			requested: []string{"latest:10"},
+			expected:  []string{"2.0.0+driver", "5.0.1+driver", "6.0.0+driver", "6.0.1+driver", "10.0.0+driver"},
+		},
+		"constraint": {
+			requested: []string{">=6.0.0"},
+			expected:  []string{"6.0.0+driver", "6.0.1+driver", "10.0.0+driver"},
+		},
+		"constraint range": {
+			requested: []string{">=5.0.0 <10.0.0"},
+			expected:  []string{"5.0.1+driver", "6.0.0+driver", "6.0.1+driver"},
+		},
+		"mixed selectors are deduplicated": {
+			requested: []string{"latest", ">6.0.0", "2.0.0+driver"},
+			expected:  []string{"2.0.0+driver", "6.0.1+driver", "10.0.0+driver"},
+		},
+		"constraint not matching": {
+			requested:   []string{">=20.0.0"},
+			expectError: true,
+		},
+		"invalid latest count": {
+			requested:   []string{"latest:0"},
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resolved, err := ResolveDriverVersions(test.requested, available)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, resolved)
+		})
+	}
+
+	_, err := ResolveDriverVersions(nil, nil)
+	assert.Error(t, err)
+}
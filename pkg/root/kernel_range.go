@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+)
+
+type rangeOperator string
+
+const (
+	opGTE rangeOperator = ">="
+	opLTE rangeOperator = "<="
+	opNEQ rangeOperator = "!="
+	opGT  rangeOperator = ">"
+	opLT  rangeOperator = "<"
+	opEQ  rangeOperator = "="
+)
+
+// Longer operators first, so that ">=" is not parsed as ">".
+var rangeOperators = []rangeOperator{opGTE, opLTE, opNEQ, opGT, opLT, opEQ}
+
+type kernelConstraint struct {
+	op    rangeOperator
+	bound kernelrelease.KernelRelease
+	// withPatch is false when the bound has no sublevel, eg: "5.10";
+	// in that case, any 5.10.x kernel is equal to the bound.
+	withPatch bool
+}
+
+// KernelRange is a set of constraints on kernel releases, that must all be satisfied,
+// eg: ">=5.8 <6.2". An empty KernelRange matches any kernel release.
+type KernelRange struct {
+	constraints []kernelConstraint
+	raw         string
+}
+
+// ParseKernelRange parses a space or comma separated list of constraints,
+// each one made of an operator (one of >=, >, <=, <, =, !=) and a kernel release.
+// A missing operator means "=".
+func ParseKernelRange(value string) (KernelRange, error) {
+	kr := KernelRange{raw: value}
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for _, field := range fields {
+		constraint := kernelConstraint{op: opEQ}
+		for _, op := range rangeOperators {
+			if after, found := strings.CutPrefix(field, string(op)); found {
+				constraint.op = op
+				field = after
+				break
+			}
+		}
+		constraint.bound = kernelrelease.FromString(field)
+		if constraint.bound.Fullversion == "" {
+			return KernelRange{}, fmt.Errorf("invalid kernel release %q in kernel range %q", field, value)
+		}
+		constraint.withPatch = strings.TrimRight(constraint.bound.Fullversion, ".+") != fmt.Sprintf("%d.%d", constraint.bound.Major, constraint.bound.Minor)
+		kr.constraints = append(kr.constraints, constraint)
+	}
+	return kr, nil
+}
+
+func (k KernelRange) IsSet() bool {
+	return len(k.constraints) > 0
+}
+
+func (k KernelRange) String() string {
+	return k.raw
+}
+
+// Contains returns whether the kernel release satisfies all the constraints.
+// Kernel releases that cannot be parsed never satisfy a non-empty range.
+func (k KernelRange) Contains(kernelRelease string) bool {
+	if !k.IsSet() {
+		return true
+	}
+	kr := kernelrelease.FromString(kernelRelease)
+	if kr.Fullversion == "" {
+		return false
+	}
+	for _, constraint := range k.constraints {
+		c := compareKernelReleases(kr, constraint)
+		var ok bool
+		switch constraint.op {
+		case opGTE:
+			ok = c >= 0
+		case opGT:
+			ok = c > 0
+		case opLTE:
+			ok = c <= 0
+		case opLT:
+			ok = c < 0
+		case opNEQ:
+			ok = c != 0
+		default:
+			ok = c == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// compareKernelReleases compares major, minor and patch versions;
+// then, only when the bound specifies it, the distro-specific extraversion,
+// numerically on its leading number (eg: ubuntu "5.15.0-1040").
+// Therefore, "5.15.3-1040-aws" is equal to a "5.15" bound, and "5.15.0-1040-aws" to a "5.15.0-1040" one.
+func compareKernelReleases(kr kernelrelease.KernelRelease, constraint kernelConstraint) int {
+	bound := constraint.bound
+	if c := cmp.Compare(kr.Major, bound.Major); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(kr.Minor, bound.Minor); c != 0 {
+		return c
+	}
+	if !constraint.withPatch {
+		return 0
+	}
+	if c := cmp.Compare(kr.Patch, bound.Patch); c != 0 {
+		return c
+	}
+	if bound.Extraversion == "" {
+		return 0
+	}
+	krExtra := kr.Extraversion
+	if num, rest := splitLeadingNumber(bound.Extraversion); num != "" && rest == "" {
+		// The bound has no flavour, eg: "5.15.0-1040"; any "5.15.0-1040-<flavour>" is equal to it
+		krExtra, _ = splitLeadingNumber(krExtra)
+	}
+	return compareNumeric(krExtra, bound.Extraversion)
+}
+
+// CompareKernelReleases semantically orders kernel releases: by major, minor and patch versions,
+// then by distro-specific extraversion (numerically when possible), then by full release string.
+// Unparseable kernel releases sort first.
+func CompareKernelReleases(a, b string) int {
+	krA, krB := kernelrelease.FromString(a), kernelrelease.FromString(b)
+	switch {
+	case krA.Fullversion == "" && krB.Fullversion != "":
+		return -1
+	case krA.Fullversion != "" && krB.Fullversion == "":
+		return 1
+	}
+	for _, c := range []int{
+		cmp.Compare(krA.Major, krB.Major),
+		cmp.Compare(krA.Minor, krB.Minor),
+		cmp.Compare(krA.Patch, krB.Patch),
+		compareNumeric(krA.Extraversion, krB.Extraversion),
+	} {
+		if c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNumeric compares the leading numbers of a and b numerically, then the rest as strings,
+// eg: "999-aws" < "1040-aws"; values not starting with a number are compared as strings.
+func compareNumeric(a, b string) int {
+	numA, restA := splitLeadingNumber(a)
+	numB, restB := splitLeadingNumber(b)
+	if numA == "" || numB == "" {
+		return strings.Compare(a, b)
+	}
+	valA, errA := strconv.ParseUint(numA, 10, 64)
+	valB, errB := strconv.ParseUint(numB, 10, 64)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	if c := cmp.Compare(valA, valB); c != 0 {
+		return c
+	}
+	return strings.Compare(restA, restB)
+}
+
+// splitLeadingNumber splits "1040-aws" in "1040" and "-aws".
+func splitLeadingNumber(s string) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return r < '0' || r > '9'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKernelRange(t *testing.T) {
+	tests := map[string]struct {
+		kernelRange string
+		expectError bool
+		matching    []string
+		notMatching []string
+	}{
+		"empty range": {
+			kernelRange: "",
+			matching:    []string{"5.10.0", "6.2.0-1-amd64", "WRONG_KERNEL"},
+		},
+		"lower and upper bounds": {
+			kernelRange: ">=5.8 <6.2",
+			matching:    []string{"5.8.0", "5.8.0-1-amd64", "5.15.0-1040-aws", "6.1.38-2-amd64", "6.1.arch1-1"},
+			notMatching: []string{"5.7.19", "4.18.0-305.el8.x86_64", "6.2.0", "6.2.0-1-amd64", "6.10.0", "WRONG_KERNEL"},
+		},
+		"comma separated constraints": {
+			kernelRange: ">5.4.0,<=5.10",
+			matching:    []string{"5.4.1", "5.10.0", "5.10.190-1-amd64"},
+			notMatching: []string{"5.4.0", "5.4.0-150-generic", "5.11.0"},
+		},
+		"extraversion bound": {
+			kernelRange: ">=5.15.0-1040 <5.15.0-1100",
+			matching:    []string{"5.15.0-1040-aws", "5.15.0-1099-gcp"},
+			notMatching: []string{"5.15.0-1039-aws", "5.15.0-1100-aws", "5.15.0-999-generic"},
+		},
+		"flavoured kernels equal to extraversion bound": {
+			kernelRange: "<=5.15.0-1040",
+			matching:    []string{"5.15.0-1040-aws", "5.15.0-1040-generic", "5.15.0-999-generic", "5.14.0"},
+			notMatching: []string{"5.15.0-1041-aws", "5.15.1"},
+		},
+		"minor version bounds": {
+			kernelRange: ">5.4 <=6.1",
+			matching:    []string{"5.5.0", "6.1.38-2-amd64", "6.1.arch1-1"},
+			notMatching: []string{"5.4.250", "6.2.0"},
+		},
+		"exact and excluded versions": {
+			kernelRange: "5.10 !=5.10.0",
+			matching:    []string{"5.10.1", "5.10.190-1-amd64"},
+			notMatching: []string{"5.10.0", "5.11.0"},
+		},
+		"invalid bound": {
+			kernelRange: ">=5.8 <WRONG",
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			kr, err := ParseKernelRange(test.kernelRange)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for _, kernelRelease := range test.matching {
+				assert.True(t, kr.Contains(kernelRelease), kernelRelease)
+			}
+			for _, kernelRelease := range test.notMatching {
+				assert.False(t, kr.Contains(kernelRelease), kernelRelease)
+			}
+		})
+	}
+}
+
+func TestCompareKernelReleases(t *testing.T) {
+	tests := map[string]struct {
+		a, b     string
+		expected int
+	}{
+		"equal":                          {a: "5.15.0-1040-aws", b: "5.15.0-1040-aws", expected: 0},
+		"major":                          {a: "4.19.0", b: "5.4.0", expected: -1},
+		"minor, numerically":             {a: "5.10.0", b: "5.4.0", expected: 1},
+		"patch":                          {a: "5.15.3", b: "5.15.12", expected: -1},
+		"extraversion, numerically":      {a: "5.15.0-1040-aws", b: "5.15.0-999-aws", expected: 1},
+		"centos extraversion":            {a: "4.18.0-477.el8.x86_64", b: "4.18.0-500.el8.x86_64", expected: -1},
+		"same extraversion, full string": {a: "5.15.0-76-generic", b: "5.15.0-76-lowlatency", expected: -1},
+		"unparseable sorts first":        {a: "foo", b: "2.6.32", expected: -1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, CompareKernelReleases(test.a, test.b))
+			assert.Equal(t, -test.expected, CompareKernelReleases(test.b, test.a))
+		})
+	}
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+type OutputFormat string
+
+const (
+	OutputText OutputFormat = "text"
+	OutputJSON OutputFormat = "json"
+	OutputYAML OutputFormat = "yaml"
+	OutputCSV  OutputFormat = "csv"
+
+	// PlanOutputStdout makes the execution plan to be written to stdout.
+	PlanOutputStdout = "-"
+)
+
+var SupportedOutputFormats = []string{
+	string(OutputText),
+	string(OutputJSON),
+	string(OutputYAML),
+	string(OutputCSV),
+}
+
+// IsStructured returns whether the output format is meant to be consumed by machines;
+// in that case, logs must not be mixed with the output.
+func (o OutputFormat) IsStructured() bool {
+	return o != "" && o != OutputText
+}
+
+// Report is the structured result of a command execution.
+type Report struct {
+	Command               string                                         `json:"command" yaml:"command"`
+	DryRun                bool                                           `json:"dryRun" yaml:"dryRun"`
+	Summary               map[Outcome]int                                `json:"summary" yaml:"summary"`
+	SummaryByArchitecture map[kernelrelease.Architecture]map[Outcome]int `json:"summaryByArchitecture" yaml:"summaryByArchitecture"`
+	Items                 []ResultItem                                   `json:"items" yaml:"items"`
+	Details               any                                            `json:"details,omitempty" yaml:"details,omitempty"`
+	Error                 string                                         `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func NewReport(command string, opts Options, err error) Report {
+	report := Report{
+		Command:               command,
+		DryRun:                opts.DryRun,
+		Summary:               make(map[Outcome]int),
+		SummaryByArchitecture: make(map[kernelrelease.Architecture]map[Outcome]int),
+		Items:                 opts.Result.Items(),
+		Details:               opts.Result.Details(),
+	}
+	if report.Items == nil {
+		report.Items = make([]ResultItem, 0)
+	}
+	for _, arch := range opts.Archs() {
+		report.SummaryByArchitecture[arch] = make(map[Outcome]int)
+	}
+	for _, item := range report.Items {
+		report.Summary[item.Outcome]++
+		if _, ok := report.SummaryByArchitecture[item.Architecture]; !ok {
+			report.SummaryByArchitecture[item.Architecture] = make(map[Outcome]int)
+		}
+		report.SummaryByArchitecture[item.Architecture][item.Outcome]++
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return report
+}
+
+func RenderReport(w io.Writer, format OutputFormat, report Report) error {
+	switch format {
+	case "", OutputText:
+		// Logs already describe what happened; only render details tables.
+		if table, ok := report.Details.(Table); ok {
+			renderTable(w, table)
+		}
+		return nil
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case OutputYAML:
+		enc := yaml.NewEncoder(w)
+		defer enc.Close()
+		return enc.Encode(report)
+	case OutputCSV:
+		var table Table = itemsTable(report.Items)
+		if detailsTable, ok := report.Details.(Table); ok {
+			table = detailsTable
+		}
+		csvW := csv.NewWriter(w)
+		_ = csvW.Write(table.Header())
+		_ = csvW.WriteAll(table.Rows())
+		return csvW.Error()
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// WriteResult renders the result collected while running a command,
+// and dumps the execution plan when in dry-run mode.
+// It always returns the command error, if any.
+func WriteResult(command string, opts Options, err error) error {
+	report := NewReport(command, opts, err)
+	Printer.Logger.Info("processed entries",
+		Printer.Logger.Args(
+			"command", command,
+			string(OutcomeSucceeded), report.Summary[OutcomeSucceeded],
+			string(OutcomeFailed), report.Summary[OutcomeFailed],
+			string(OutcomeSkipped), report.Summary[OutcomeSkipped],
+			string(OutcomePlanned), report.Summary[OutcomePlanned],
+			string(OutcomeCancelled), report.Summary[OutcomeCancelled]))
+
+	if opts.DryRun && opts.PlanOutput != "" {
+		if pErr := writePlan(opts.PlanOutput, report); pErr != nil && err == nil {
+			err = pErr
+		}
+	}
+	out := os.Stdout
+	if opts.PlanToStdout() {
+		// Only structured outputs are rejected along with a stdout plan: keep stdout for the plan
+		out = os.Stderr
+	}
+	if rErr := RenderReport(out, opts.Output, report); rErr != nil && err == nil {
+		err = rErr
+	}
+	return err
+}
+
+// writePlan dumps the report as JSON to the requested path. "-" means stdout.
+func writePlan(path string, report Report) error {
+	if path == PlanOutputStdout {
+		return RenderReport(os.Stdout, OutputJSON, report)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = RenderReport(f, OutputJSON, report)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+func renderTable(w io.Writer, t Table) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(t.Header())
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(t.Rows())
+	table.Render() // Send output
+}
+
+type itemsTable []ResultItem
+
+func (i itemsTable) Header() []string {
+	return []string{"Architecture", "DriverVersion", "Path", "Action", "Outcome", "Error"}
+}
+
+func (i itemsTable) Rows() [][]string {
+	rows := make([][]string, 0, len(i))
+	for _, item := range i {
+		rows = append(rows, []string{item.Architecture.String(), item.DriverVersion, item.Path, item.Action, string(item.Outcome), item.Error})
+	}
+	return rows
+}
+
+// PlanToStdout returns whether the execution plan is written to stdout;
+// in that case, nothing else must be written there.
+func (o Options) PlanToStdout() bool {
+	return o.DryRun && o.PlanOutput == PlanOutputStdout
+}
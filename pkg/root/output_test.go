@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type testTable struct {
+	Names []string `json:"names" yaml:"names"`
+}
+
+func (tt testTable) Header() []string {
+	return []string{"Name"}
+}
+
+func (tt testTable) Rows() [][]string {
+	rows := make([][]string, 0)
+	for _, name := range tt.Names {
+		rows = append(rows, []string{name})
+	}
+	return rows
+}
+
+func TestRenderReport(t *testing.T) {
+	result := NewResult()
+	result.Record(ResultItem{
+		Action:        "validating",
+		Architecture:  "amd64",
+		DriverVersion: "1.0.0+driver",
+		Path:          "centos_5.10.0_1.yaml",
+	}, nil)
+	result.Record(ResultItem{
+		Action:        "validating",
+		Architecture:  "amd64",
+		DriverVersion: "1.0.0+driver",
+		Path:          "centos_5.15.0_1.yaml",
+	}, errors.New("wrong arch"))
+	result.Record(ResultItem{
+		Action:        "validating",
+		Architecture:  "arm64",
+		DriverVersion: "1.0.0+driver",
+		Path:          "centos_5.15.0_1.yaml",
+	}, nil)
+	opts := Options{
+		Architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+		Result:        result,
+	}
+	report := NewReport("dbg-go configs validate", opts, errors.New("wrong arch"))
+	assert.Equal(t, map[Outcome]int{OutcomeSucceeded: 2, OutcomeFailed: 1}, report.Summary)
+	assert.Equal(t, map[kernelrelease.Architecture]map[Outcome]int{
+		"amd64": {OutcomeSucceeded: 1, OutcomeFailed: 1},
+		"arm64": {OutcomeSucceeded: 1},
+	}, report.SummaryByArchitecture)
+
+	var buf bytes.Buffer
+	err := RenderReport(&buf, OutputJSON, report)
+	assert.NoError(t, err)
+	var jsonReport Report
+	err = json.Unmarshal(buf.Bytes(), &jsonReport)
+	assert.NoError(t, err)
+	assert.Equal(t, report.Items, jsonReport.Items)
+	assert.Equal(t, "wrong arch", jsonReport.Error)
+	assert.Equal(t, 1, jsonReport.Summary[OutcomeFailed])
+
+	buf.Reset()
+	err = RenderReport(&buf, OutputYAML, report)
+	assert.NoError(t, err)
+	var yamlReport Report
+	err = yaml.Unmarshal(buf.Bytes(), &yamlReport)
+	assert.NoError(t, err)
+	assert.Equal(t, report.Items, yamlReport.Items)
+
+	buf.Reset()
+	err = RenderReport(&buf, OutputCSV, report)
+	assert.NoError(t, err)
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+	assert.Equal(t, []string{"amd64", "1.0.0+driver", "centos_5.15.0_1.yaml", "validating", "failed", "wrong arch"}, records[2])
+
+	// Text output renders nothing but details tables
+	buf.Reset()
+	err = RenderReport(&buf, OutputText, report)
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+
+	// When details are a table, csv renders them instead of items
+	result.SetDetails(testTable{Names: []string{"foo", "bar"}})
+	report = NewReport("dbg-go configs stats", opts, nil)
+	buf.Reset()
+	err = RenderReport(&buf, OutputCSV, report)
+	assert.NoError(t, err)
+	records, err = csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"Name"}, {"foo"}, {"bar"}}, records)
+
+	buf.Reset()
+	err = RenderReport(&buf, OutputText, report)
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "NAME")
+	assert.Contains(t, buf.String(), "foo")
+
+	err = RenderReport(&buf, "xml", report)
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+)
+
+type Outcome string
+
+const (
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+	OutcomeSkipped   Outcome = "skipped"
+	OutcomePlanned   Outcome = "planned"
+	// OutcomeCancelled is used for entries whose processing was interrupted,
+	// or that were never processed because the run was interrupted.
+	OutcomeCancelled Outcome = "cancelled"
+)
+
+// ErrSkipped can be returned (possibly wrapped) by a RowWorker
+// to signal that an entry was intentionally not processed.
+var ErrSkipped = errors.New("skipped")
+
+// ErrUnchanged is a skip error for files whose content is already up to date.
+var ErrUnchanged = fmt.Errorf("%w: unchanged", ErrSkipped)
+
+// IgnoredErr wraps a RowWorker error that must be reported,
+// but must not break the loop.
+type IgnoredErr struct {
+	Err error
+}
+
+func (i *IgnoredErr) Error() string {
+	return i.Err.Error()
+}
+
+func (i *IgnoredErr) Unwrap() error {
+	return i.Err
+}
+
+// LoopError returns the RowWorker error that must break a loop, if any.
+func LoopError(err error) error {
+	var ignoredErr *IgnoredErr
+	if errors.Is(err, ErrSkipped) || errors.As(err, &ignoredErr) {
+		return nil
+	}
+	return err
+}
+
+// Table is implemented by command specific details that are better rendered as a table.
+type Table interface {
+	Header() []string
+	Rows() [][]string
+}
+
+type ResultItem struct {
+	Action        string                     `json:"action" yaml:"action"`
+	Architecture  kernelrelease.Architecture `json:"architecture" yaml:"architecture"`
+	DriverVersion string                     `json:"driverVersion" yaml:"driverVersion"`
+	Path          string                     `json:"path" yaml:"path"`
+	// Source is where the entry comes from, when relevant, eg: the kernel-crawler source of a generated config.
+	Source  string  `json:"source,omitempty" yaml:"source,omitempty"`
+	Outcome Outcome `json:"outcome" yaml:"outcome"`
+	Error   string  `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Result collects the entries processed by a command, together with their outcome.
+// It is safe for concurrent use; a nil Result silently discards everything.
+type Result struct {
+	mu      sync.Mutex
+	items   []ResultItem
+	details any
+}
+
+func NewResult() *Result {
+	return &Result{items: make([]ResultItem, 0)}
+}
+
+func (r *Result) add(item ResultItem) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = append(r.items, item)
+}
+
+// Plan records an entry that would be processed, if not in dry-run mode.
+func (r *Result) Plan(item ResultItem) {
+	item.Outcome = OutcomePlanned
+	r.add(item)
+}
+
+// Record records the outcome of processing an entry, given the error returned while processing it.
+func (r *Result) Record(item ResultItem, err error) {
+	item.Outcome = OutcomeSucceeded
+	if err != nil {
+		item.Error = err.Error()
+		switch {
+		case errors.Is(err, ErrSkipped):
+			item.Outcome = OutcomeSkipped
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			item.Outcome = OutcomeCancelled
+		default:
+			item.Outcome = OutcomeFailed
+		}
+	}
+	r.add(item)
+}
+
+// SetDetails attaches command specific details to the result, eg: stats.
+func (r *Result) SetDetails(details any) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.details = details
+}
+
+// Items returns a sorted copy of the result items,
+// so that the same result is always rendered the same way.
+func (r *Result) Items() []ResultItem {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	items := slices.Clone(r.items)
+	r.mu.Unlock()
+	slices.SortStableFunc(items, func(a, b ResultItem) int {
+		if c := strings.Compare(a.Architecture.String(), b.Architecture.String()); c != 0 {
+			return c
+		}
+		if c := CompareDriverVersions(a.DriverVersion, b.DriverVersion); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return items
+}
+
+func (r *Result) Details() any {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.details
+}
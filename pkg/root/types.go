@@ -15,20 +15,29 @@ limitations under the License.
 package root
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
 	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 	"github.com/spf13/viper"
 )
 
-type RowWorker func(driverVersion, path string) error
+// targetNameRegex splits a config or driver file name, without the driver name prefix,
+// into distro, kernel release and kernel version.
+var targetNameRegex = regexp.MustCompile(`^([^_]*)_(.*)_([^_]*?)\.[^._]*$`)
+
+type RowWorker func(ctx context.Context, arch kernelrelease.Architecture, driverVersion, path string) error
 
 type PathBuilder func(opts Options, driverVersion, configName string) string
 
 type Looper interface {
-	LoopFiltered(opts Options, message, tag string, worker RowWorker) error
+	LoopFiltered(ctx context.Context, opts Options, message, tag string, worker RowWorker) error
 }
 
 type FsLooper struct {
@@ -43,6 +52,13 @@ type Target struct {
 	Distro        builder.Type
 	KernelRelease string
 	KernelVersion string
+	// Exclusion patterns; entries matching any of them are skipped,
+	// even if matched by the positive filters above.
+	ExcludeDistro        []string
+	ExcludeKernelRelease []string
+	ExcludeKernelVersion []string
+	// KernelRange semantically filters kernel releases, eg: ">=5.8 <6.2".
+	KernelRange KernelRange
 }
 
 func (t Target) IsSet() bool {
@@ -50,21 +66,43 @@ func (t Target) IsSet() bool {
 }
 
 func (t Target) toGlob() string {
-	// Empty filters fallback at ".*" since we are using a regex match below
-	if t.Distro == "" {
-		t.Distro = "*"
+	// Only literal filters can be used in the glob;
+	// everything else is matched by nameFilter once files are listed.
+	return fmt.Sprintf("%s_%s_%s.*",
+		literalOrWildcard(t.Distro.String()),
+		literalOrWildcard(t.KernelRelease),
+		literalOrWildcard(t.KernelVersion))
+}
+
+// literalOrWildcard returns the pattern itself when it has no regex meta characters,
+// or "*" otherwise. Dots are allowed since a literal dot in a glob
+// only matches a subset of what the regex dot matches.
+func literalOrWildcard(pattern string) string {
+	if pattern == "" || strings.ContainsAny(pattern, `\+*?()|[]{}^$`) {
+		return "*"
 	}
-	if t.KernelRelease == "" {
-		t.KernelRelease = "*"
+	return pattern
+}
+
+// nameFilter checks a config or driver file name,
+// ie: {drivername_}distro_kernelrelease_kernelversion.{yaml,ko,o}, against the target.
+// Unlike DistroFilter, it does not check that the distro is supported:
+// files already on disk are always processed.
+func (t Target) nameFilter(name, driverName string) bool {
+	if filepath.Ext(name) != ".yaml" {
+		name = strings.TrimPrefix(name, driverName+"_")
 	}
-	if t.KernelVersion == "" {
-		t.KernelVersion = "*"
+	matches := targetNameRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return false
 	}
-	return fmt.Sprintf("%s_%s_%s.*", t.Distro, t.KernelRelease, t.KernelVersion)
+	return matchFilter(t.Distro.String(), t.ExcludeDistro, matches[1]) &&
+		t.KernelReleaseFilter(matches[2]) &&
+		t.KernelVersionFilter(matches[3])
 }
 
 func (t Target) DistroFilter(distro string) bool {
-	matched, _ := regexp.MatchString(t.Distro.String(), distro)
+	matched := matchFilter(t.Distro.String(), t.ExcludeDistro, distro)
 	// check if key is actually supported
 	if matched {
 		_, ok := SupportedDistros[builder.Type(distro)]
@@ -74,37 +112,121 @@ func (t Target) DistroFilter(distro string) bool {
 }
 
 func (t Target) KernelReleaseFilter(kernelrelease string) bool {
-	matched, _ := regexp.MatchString(t.KernelRelease, kernelrelease)
-	return matched
+	return matchFilter(t.KernelRelease, t.ExcludeKernelRelease, kernelrelease) &&
+		t.KernelRange.Contains(kernelrelease)
 }
 
 func (t Target) KernelVersionFilter(kernelversion string) bool {
-	matched, _ := regexp.MatchString(t.KernelVersion, kernelversion)
-	return matched
+	return matchFilter(t.KernelVersion, t.ExcludeKernelVersion, kernelversion)
+}
+
+// matchFilter returns whether value matches the include pattern and none of the exclude ones.
+func matchFilter(include string, excludes []string, value string) bool {
+	if matched, _ := regexp.MatchString(include, value); !matched {
+		return false
+	}
+	for _, exclude := range excludes {
+		if matched, _ := regexp.MatchString(exclude, value); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// JoinPatterns merges multiple include patterns into a single regex
+// matching any of them.
+func JoinPatterns(patterns []string) string {
+	return strings.Join(patterns, "|")
 }
 
 type Options struct {
 	DryRun        bool
+	PlanOutput    string
+	Output        OutputFormat
+	Result        *Result
+	Jobs          int
 	RepoRoot      string
 	Architecture  kernelrelease.Architecture
+	Architectures []kernelrelease.Architecture
 	DriverName    string
 	DriverVersion []string
 	Target
 }
 
+// ParallelJobs returns the number of workers loopers are allowed to run concurrently.
+// Unset or invalid values fallback at a single, sequential, worker.
+func (o Options) ParallelJobs() int {
+	if o.Jobs < 1 {
+		return 1
+	}
+	return o.Jobs
+}
+
+// Archs returns the list of architectures to run against;
+// it fallbacks at the single Architecture when no list is set.
+func (o Options) Archs() []kernelrelease.Architecture {
+	if len(o.Architectures) == 0 {
+		return []kernelrelease.Architecture{o.Architecture}
+	}
+	return o.Architectures
+}
+
+// ForArchitecture returns a copy of the options bound to a single architecture.
+func (o Options) ForArchitecture(arch kernelrelease.Architecture) Options {
+	o.Architecture = arch
+	o.Architectures = []kernelrelease.Architecture{arch}
+	return o
+}
+
+// ParseArchitectures validates the requested architectures;
+// "all" expands to all the supported ones.
+func ParseArchitectures(values []string) ([]kernelrelease.Architecture, error) {
+	if slices.Contains(values, AllArchitectures) {
+		return slices.Sorted(maps.Keys(kernelrelease.SupportedArchs)), nil
+	}
+	archs := make([]kernelrelease.Architecture, 0, len(values))
+	for _, value := range values {
+		arch := kernelrelease.Architecture(value)
+		if _, present := kernelrelease.SupportedArchs[arch]; !present {
+			return nil, fmt.Errorf("arch %s is not supported", arch)
+		}
+		if !slices.Contains(archs, arch) {
+			archs = append(archs, arch)
+		}
+	}
+	if len(archs) == 0 {
+		return nil, fmt.Errorf("no architecture requested")
+	}
+	return archs, nil
+}
+
 func LoadRootOptions() Options {
+	// Architectures and kernel range are already validated by the root command
+	archs, _ := ParseArchitectures(viper.GetStringSlice("architecture"))
+	kernelRange, _ := ParseKernelRange(viper.GetString("kernel-range"))
 	opts := Options{
 		DryRun:        viper.GetBool("dry-run"),
+		PlanOutput:    viper.GetString("plan-output"),
+		Output:        OutputFormat(viper.GetString("output")),
+		Result:        NewResult(),
+		Jobs:          viper.GetInt("jobs"),
 		DriverName:    viper.GetString("driver-name"),
 		RepoRoot:      viper.GetString("repo-root"),
-		Architecture:  kernelrelease.Architecture(viper.GetString("architecture")),
+		Architectures: archs,
 		DriverVersion: viper.GetStringSlice("driver-version"),
 		Target: Target{
-			Distro:        builder.Type(viper.GetString("target-distro")),
-			KernelRelease: viper.GetString("target-kernelrelease"),
-			KernelVersion: viper.GetString("target-kernelversion"),
+			Distro:               builder.Type(JoinPatterns(viper.GetStringSlice("target-distro"))),
+			KernelRelease:        JoinPatterns(viper.GetStringSlice("target-kernelrelease")),
+			KernelVersion:        JoinPatterns(viper.GetStringSlice("target-kernelversion")),
+			ExcludeDistro:        viper.GetStringSlice("exclude-distro"),
+			ExcludeKernelRelease: viper.GetStringSlice("exclude-kernelrelease"),
+			ExcludeKernelVersion: viper.GetStringSlice("exclude-kernelversion"),
+			KernelRange:          kernelRange,
 		},
 	}
+	if len(archs) > 0 {
+		opts.Architecture = archs[0]
+	}
 	Printer.Logger.Debug("loaded root options",
 		Printer.Logger.Args("opts", opts))
 	return opts
@@ -15,32 +15,87 @@ limitations under the License.
 package root
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
 )
 
-func (f *FsLooper) LoopFiltered(opts Options, message, tag string, worker RowWorker) error {
+// ListFiltered returns the files, built by builder, matching the target filters,
+// for each architecture and driver version; action is set on the returned entries.
+func ListFiltered(builder PathBuilder, opts Options, action string) ([]ResultItem, error) {
 	configNameGlob := opts.Target.toGlob()
-	for _, driverVersion := range opts.DriverVersion {
-		path := f.builder(opts, driverVersion, configNameGlob)
-		files, err := filepath.Glob(path)
-		if err != nil {
-			return err
-		}
-		for _, file := range files {
-			Printer.Logger.Info(message,
-				Printer.Logger.Args(tag, file))
-			if opts.DryRun {
-				Printer.Logger.Info("skipping because of dry-run.")
-				return nil
-			}
-			err = worker(driverVersion, file)
+	var entries []ResultItem
+	for _, arch := range opts.Archs() {
+		archOpts := opts.ForArchitecture(arch)
+		for _, driverVersion := range opts.DriverVersion {
+			path := builder(archOpts, driverVersion, configNameGlob)
+			files, err := filepath.Glob(path)
 			if err != nil {
-				return err
+				return nil, err
+			}
+			for _, file := range files {
+				if !opts.Target.nameFilter(filepath.Base(file), opts.DriverName) {
+					continue
+				}
+				entries = append(entries, ResultItem{
+					Action:        action,
+					Architecture:  arch,
+					DriverVersion: driverVersion,
+					Path:          file,
+				})
 			}
 		}
 	}
-	return nil
+	return entries, nil
+}
+
+func (f *FsLooper) LoopFiltered(ctx context.Context, opts Options, message, tag string, worker RowWorker) error {
+	entries, err := ListFiltered(f.builder, opts, message)
+	if err != nil {
+		return err
+	}
+
+	// Fan out workers over a bounded pool;
+	// the first failing worker, or the run being interrupted, cancels the context,
+	// so that no new entry gets processed.
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.ParallelJobs())
+	for _, entry := range entries {
+		if opts.DryRun {
+			// Just enumerate the entries that would be processed
+			Printer.Logger.Info("skipping because of dry-run.",
+				Printer.Logger.Args(tag, entry.Path))
+			opts.Result.Plan(entry)
+			continue
+		}
+		if gCtx.Err() != nil {
+			// Report entries that were never processed
+			opts.Result.Record(entry, gCtx.Err())
+			continue
+		}
+		g.Go(func() error {
+			if gCtx.Err() != nil {
+				opts.Result.Record(entry, gCtx.Err())
+				return nil
+			}
+			Printer.Logger.Info(message,
+				Printer.Logger.Args(tag, entry.Path))
+			err := worker(gCtx, entry.Architecture, entry.DriverVersion, entry.Path)
+			opts.Result.Record(entry, err)
+			return LoopError(err)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	// Make sure that an interrupted run is not reported as successful
+	return ctx.Err()
 }
 
 func BuildConfigPath(opts Options, driverVersion, configName string) string {
@@ -65,3 +120,62 @@ func BuildOutputPath(opts Options, driverVersion, outputName string) string {
 		opts.Architecture.ToNonDeb(),
 		fullName)
 }
+
+// WriteAction returns whether writing data to path is going to create or update it,
+// or an empty action if its content is already the same.
+func WriteAction(path string, data []byte) (string, error) {
+	existing, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return ActionCreating, nil
+	case err != nil:
+		return "", err
+	case bytes.Equal(existing, data):
+		return "", nil
+	default:
+		return ActionUpdating, nil
+	}
+}
+
+// WriteFileAtomic writes data to a temporary file in the same folder, then renames it to path,
+// so that readers never see a partially written file, and a failed write never corrupts an existing one.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	// No-op once renamed
+	defer os.Remove(tmpPath)
+	return os.Rename(tmpPath, path)
+}
+
+// CreateFileAtomic is like WriteFileAtomic, but fails with fs.ErrExist if path already exists;
+// the existence check and the creation are a single atomic operation.
+func CreateFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	// Unlike rename, link never replaces an existing file
+	return os.Link(tmpPath, path)
+}
+
+// writeTempFile writes data to a temporary file in the same folder as path, returning its name.
+func writeTempFile(path string, data []byte, perm os.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Chmod(perm)
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,482 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package root
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestConfigs(t *testing.T, opts Options, configNames []string) {
+	for _, driverVersion := range opts.DriverVersion {
+		configPath := BuildConfigPath(opts, driverVersion, "")
+		err := os.MkdirAll(configPath, 0700)
+		assert.NoError(t, err)
+		for _, configName := range configNames {
+			err = os.WriteFile(filepath.Join(configPath, configName), nil, 0644)
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestFsLooperParallel(t *testing.T) {
+	configNames := []string{
+		"centos_5.10.0_1.yaml",
+		"centos_5.15.0_1.yaml",
+		"ubuntu_5.15.0_13.yaml",
+		"bottlerocket_5.15.25_1.yaml",
+		"debian_6.1.38-2-amd64_1.yaml",
+	}
+
+	tests := map[string]struct {
+		opts            Options
+		expectedEntries int
+	}{
+		"sequential loop": {
+			opts: Options{
+				Jobs:          1,
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver", "2.0.0+driver"},
+			},
+			expectedEntries: 10,
+		},
+		"parallel loop": {
+			opts: Options{
+				Jobs:          3,
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver", "2.0.0+driver"},
+			},
+			expectedEntries: 10,
+		},
+		"parallel loop with more jobs than entries": {
+			opts: Options{
+				Jobs:          32,
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver"},
+			},
+			expectedEntries: 5,
+		},
+		"parallel loop filtered by distro": {
+			opts: Options{
+				Jobs:          2,
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver"},
+				Target: Target{
+					Distro: "centos",
+				},
+			},
+			expectedEntries: 2,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.opts.RepoRoot = t.TempDir()
+			createTestConfigs(t, test.opts, configNames)
+
+			var (
+				mu       sync.Mutex
+				visited  = make(map[string]struct{})
+				running  atomic.Int32
+				maxInUse atomic.Int32
+			)
+			looper := NewFsLooper(BuildConfigPath)
+			err := looper.LoopFiltered(context.Background(), test.opts, "looping", "config", func(_ context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+				inUse := running.Add(1)
+				defer running.Add(-1)
+				for {
+					curMax := maxInUse.Load()
+					if inUse <= curMax || maxInUse.CompareAndSwap(curMax, inUse) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				visited[path] = struct{}{}
+				mu.Unlock()
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Len(t, visited, test.expectedEntries)
+			assert.LessOrEqual(t, int(maxInUse.Load()), test.opts.ParallelJobs())
+		})
+	}
+}
+
+func TestFsLooperFailFast(t *testing.T) {
+	opts := Options{
+		Jobs:          2,
+		RepoRoot:      t.TempDir(),
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+	}
+	configNames := make([]string, 0)
+	for _, kr := range []string{"5.1.0", "5.2.0", "5.3.0", "5.4.0", "5.5.0", "5.6.0", "5.7.0", "5.8.0"} {
+		configNames = append(configNames, "centos_"+kr+"_1.yaml")
+	}
+	createTestConfigs(t, opts, configNames)
+
+	errWorker := errors.New("worker failure")
+	var processed atomic.Int32
+	looper := NewFsLooper(BuildConfigPath)
+	err := looper.LoopFiltered(context.Background(), opts, "looping", "config", func(_ context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+		processed.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return errWorker
+	})
+	assert.ErrorIs(t, err, errWorker)
+	// First failure must stop new entries from being processed
+	assert.Less(t, int(processed.Load()), len(configNames))
+}
+
+func TestFsLooperDryRunPlan(t *testing.T) {
+	opts := Options{
+		DryRun:        true,
+		Result:        NewResult(),
+		Jobs:          4,
+		RepoRoot:      t.TempDir(),
+		Architecture:  "amd64",
+		DriverVersion: []string{"2.0.0+driver", "1.0.0+driver"},
+		Target: Target{
+			Distro: "centos",
+		},
+	}
+	createTestConfigs(t, opts, []string{
+		"centos_5.15.0_1.yaml",
+		"centos_5.10.0_1.yaml",
+		"ubuntu_5.15.0_13.yaml",
+	})
+
+	looper := NewFsLooper(BuildConfigPath)
+	err := looper.LoopFiltered(context.Background(), opts, "removing file", "config", func(_ context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+		t.Fatalf("worker must not be called in dry-run mode: %s", path)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// Items span all driver versions, are filtered and sorted
+	items := opts.Result.Items()
+	assert.Len(t, items, 4)
+	expectedDriverVersions := []string{"1.0.0+driver", "1.0.0+driver", "2.0.0+driver", "2.0.0+driver"}
+	for i, item := range items {
+		assert.Equal(t, "removing file", item.Action)
+		assert.Equal(t, OutcomePlanned, item.Outcome)
+		assert.Equal(t, expectedDriverVersions[i], item.DriverVersion)
+		assert.Contains(t, item.Path, "centos_")
+	}
+	assert.Contains(t, items[0].Path, "centos_5.10.0_1.yaml")
+
+	opts.PlanOutput = PlanOutputStdout
+	assert.True(t, opts.PlanToStdout())
+	planOutput := filepath.Join(t.TempDir(), "plan.json")
+	opts.PlanOutput = planOutput
+	assert.False(t, opts.PlanToStdout())
+	err = WriteResult("dbg-go configs cleanup", opts, nil)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(planOutput)
+	assert.NoError(t, err)
+	var report Report
+	err = json.Unmarshal(data, &report)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, items, report.Items)
+	assert.Equal(t, 4, report.Summary[OutcomePlanned])
+}
+
+func TestFsLooperOutcomes(t *testing.T) {
+	opts := Options{
+		Result:        NewResult(),
+		RepoRoot:      t.TempDir(),
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+	}
+	createTestConfigs(t, opts, []string{
+		"centos_5.10.0_1.yaml",
+		"centos_5.15.0_1.yaml",
+		"ubuntu_5.15.0_13.yaml",
+	})
+
+	looper := NewFsLooper(BuildConfigPath)
+	err := looper.LoopFiltered(context.Background(), opts, "building driver", "config", func(_ context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+		switch filepath.Base(path) {
+		case "centos_5.10.0_1.yaml":
+			return fmt.Errorf("%w: already built", ErrSkipped)
+		case "centos_5.15.0_1.yaml":
+			return &IgnoredErr{Err: errors.New("build failed")}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	report := NewReport("dbg-go configs build", opts, nil)
+	assert.Equal(t, map[Outcome]int{
+		OutcomeSkipped:   1,
+		OutcomeFailed:    1,
+		OutcomeSucceeded: 1,
+	}, report.Summary)
+	assert.Equal(t, "build failed", report.Items[1].Error)
+}
+
+func TestFsLooperMultiArch(t *testing.T) {
+	opts := Options{
+		Result:        NewResult(),
+		Jobs:          2,
+		RepoRoot:      t.TempDir(),
+		Architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+		DriverVersion: []string{"1.0.0+driver"},
+	}
+	createTestConfigs(t, opts.ForArchitecture("amd64"), []string{
+		"centos_5.10.0_1.yaml",
+		"ubuntu_5.15.0_13.yaml",
+	})
+	createTestConfigs(t, opts.ForArchitecture("arm64"), []string{
+		"centos_5.10.0_1.yaml",
+	})
+
+	looper := NewFsLooper(BuildConfigPath)
+	err := looper.LoopFiltered(context.Background(), opts, "looping", "config", func(_ context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+		// Each entry must be processed for the architecture it belongs to
+		assert.Contains(t, path, "/"+arch.ToNonDeb()+"/")
+		return nil
+	})
+	assert.NoError(t, err)
+
+	report := NewReport("dbg-go configs validate", opts, nil)
+	assert.Len(t, report.Items, 3)
+	assert.Equal(t, map[kernelrelease.Architecture]map[Outcome]int{
+		"amd64": {OutcomeSucceeded: 2},
+		"arm64": {OutcomeSucceeded: 1},
+	}, report.SummaryByArchitecture)
+}
+
+func TestParseArchitectures(t *testing.T) {
+	tests := map[string]struct {
+		values      []string
+		expected    []kernelrelease.Architecture
+		expectError bool
+	}{
+		"single arch": {
+			values:   []string{"arm64"},
+			expected: []kernelrelease.Architecture{"arm64"},
+		},
+		"multiple archs are deduplicated": {
+			values:   []string{"arm64", "amd64", "arm64"},
+			expected: []kernelrelease.Architecture{"arm64", "amd64"},
+		},
+		"all archs": {
+			values:   []string{"amd64", AllArchitectures},
+			expected: []kernelrelease.Architecture{"amd64", "arm64"},
+		},
+		"unsupported arch": {
+			values:      []string{"amd64", "WRONG_ARCH"},
+			expectError: true,
+		},
+		"no arch": {
+			values:      nil,
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			archs, err := ParseArchitectures(test.values)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, archs)
+		})
+	}
+}
+
+func TestFsLooperFilters(t *testing.T) {
+	configNames := []string{
+		"centos_5.10.0_1.yaml",
+		"centos_5.15.0_1.yaml",
+		"ubuntu_5.15.0-1040-aws_13.yaml",
+		"ubuntu_5.15.0-1040-generic_13.yaml",
+		"bottlerocket_5.15.25_1.yaml",
+	}
+
+	tests := map[string]struct {
+		target   Target
+		expected []string
+	}{
+		"no filters": {
+			expected: configNames,
+		},
+		"literal distro": {
+			target:   Target{Distro: "centos"},
+			expected: []string{"centos_5.10.0_1.yaml", "centos_5.15.0_1.yaml"},
+		},
+		"multiple distros": {
+			target:   Target{Distro: builder.Type(JoinPatterns([]string{"centos", "bottle.*"}))},
+			expected: []string{"bottlerocket_5.15.25_1.yaml", "centos_5.10.0_1.yaml", "centos_5.15.0_1.yaml"},
+		},
+		"excluded distro": {
+			target:   Target{ExcludeDistro: []string{"bottlerocket"}},
+			expected: []string{"centos_5.10.0_1.yaml", "centos_5.15.0_1.yaml", "ubuntu_5.15.0-1040-aws_13.yaml", "ubuntu_5.15.0-1040-generic_13.yaml"},
+		},
+		"excluded kernel release flavour": {
+			target:   Target{Distro: "ubuntu", ExcludeKernelRelease: []string{"-aws$"}},
+			expected: []string{"ubuntu_5.15.0-1040-generic_13.yaml"},
+		},
+		"multiple exclusions": {
+			target: Target{
+				KernelRelease:        "^5.15",
+				ExcludeDistro:        []string{"ubuntu"},
+				ExcludeKernelVersion: []string{"^13$"},
+			},
+			expected: []string{"bottlerocket_5.15.25_1.yaml", "centos_5.15.0_1.yaml"},
+		},
+		"kernel range": {
+			target:   Target{KernelRange: mustParseKernelRange(t, ">=5.15 <5.15.25")},
+			expected: []string{"centos_5.15.0_1.yaml", "ubuntu_5.15.0-1040-aws_13.yaml", "ubuntu_5.15.0-1040-generic_13.yaml"},
+		},
+		"kernel range with regex filters": {
+			target: Target{
+				KernelRelease: "-aws$|^5.10",
+				KernelRange:   mustParseKernelRange(t, ">5.10"),
+			},
+			expected: []string{"ubuntu_5.15.0-1040-aws_13.yaml"},
+		},
+		"excluded kernel version": {
+			target:   Target{ExcludeKernelVersion: []string{"1"}},
+			expected: []string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := Options{
+				Result:        NewResult(),
+				RepoRoot:      t.TempDir(),
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver"},
+				Target:        test.target,
+			}
+			createTestConfigs(t, opts, configNames)
+
+			looper := NewFsLooper(BuildConfigPath)
+			err := looper.LoopFiltered(context.Background(), opts, "looping", "config", func(_ context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+				return nil
+			})
+			assert.NoError(t, err)
+			visited := make([]string, 0)
+			for _, item := range opts.Result.Items() {
+				visited = append(visited, filepath.Base(item.Path))
+			}
+			assert.ElementsMatch(t, test.expected, visited)
+		})
+	}
+}
+
+func mustParseKernelRange(t *testing.T, value string) KernelRange {
+	kr, err := ParseKernelRange(value)
+	assert.NoError(t, err)
+	return kr
+}
+
+func TestTargetNameFilter(t *testing.T) {
+	target := Target{Distro: "centos", ExcludeKernelRelease: []string{"el8"}}
+	assert.True(t, target.nameFilter("centos_5.14.0-325.el9.x86_64_1.yaml", "falco"))
+	assert.True(t, target.nameFilter("falco_centos_5.14.0-325.el9.x86_64_1.ko", "falco"))
+	assert.True(t, target.nameFilter("falco_centos_5.14.0-325.el9.x86_64_1.o", "falco"))
+	assert.False(t, target.nameFilter("falco_centos_4.18.0-305.el8.x86_64_1.ko", "falco"))
+	assert.False(t, target.nameFilter("falco_ubuntu_5.15.0_1.ko", "falco"))
+	assert.False(t, target.nameFilter("malformed.yaml", "falco"))
+}
+
+func TestFsLooperCancel(t *testing.T) {
+	opts := Options{
+		Result:        NewResult(),
+		Jobs:          1,
+		RepoRoot:      t.TempDir(),
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+	}
+	configNames := []string{
+		"centos_5.10.0_1.yaml",
+		"centos_5.15.0_1.yaml",
+		"ubuntu_5.15.0_13.yaml",
+		"bottlerocket_5.15.25_1.yaml",
+	}
+	createTestConfigs(t, opts, configNames)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var processed atomic.Int32
+	looper := NewFsLooper(BuildConfigPath)
+	err := looper.LoopFiltered(ctx, opts, "looping", "config", func(ctx context.Context, arch kernelrelease.Architecture, driverVersion, path string) error {
+		if processed.Add(1) == 1 {
+			// Simulate an interruption while the first entry is being processed
+			cancel()
+			return nil
+		}
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), processed.Load())
+
+	// All entries are reported, either as completed or as cancelled
+	report := NewReport("dbg-go configs build", opts, err)
+	assert.Equal(t, map[Outcome]int{
+		OutcomeSucceeded: 1,
+		OutcomeCancelled: len(configNames) - 1,
+	}, report.Summary)
+}
+
+func TestCreateFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	var (
+		wg      sync.WaitGroup
+		created atomic.Int32
+	)
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := CreateFileAtomic(path, []byte(fmt.Sprintf("writer: %d\n", i)), 0o644)
+			if err == nil {
+				created.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, fs.ErrExist)
+		}()
+	}
+	wg.Wait()
+	// Exactly one writer wins, and no temporary file is left behind
+	assert.Equal(t, int32(1), created.Load())
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+}
@@ -15,45 +15,73 @@ limitations under the License.
 package stats
 
 import (
-	"github.com/falcosecurity/dbg-go/pkg/root"
-	"os"
+	"context"
+	"slices"
 	"strconv"
 
-	"github.com/olekukonko/tablewriter"
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
-func Run(opts Options, statter Statter) error {
+func Run(ctx context.Context, opts Options, statter Statter) error {
 	root.Printer.Logger.Info(statter.Info())
-	driverStatsByVersion, err := statter.GetDriverStats(opts.Options)
-	if err != nil {
-		return err
+	driverVersions := slices.Clone(opts.DriverVersion)
+	root.SortDriverVersions(driverVersions)
+	details := statsDetails{
+		Versions:             make([]versionStats, 0, len(opts.DriverVersion)*len(opts.Archs())),
+		TotalsByArchitecture: make(map[kernelrelease.Architecture]driverStats),
 	}
+	for _, arch := range opts.Archs() {
+		driverStatsByVersion, err := statter.GetDriverStats(ctx, opts.ForArchitecture(arch))
+		if err != nil {
+			return err
+		}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Version", "Modules", "Probes"})
-	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-	table.SetCenterSeparator("|")
-
-	totalDriverStats := driverStats{}
-
-	data := make([]string, 3)
-	// Keep keys sorted
-	// (looping directly on the map {key,value} tuples gives wrong sorting sometimes).
-	for _, key := range opts.DriverVersion {
-		stat := driverStatsByVersion[key]
-		data[0] = key
-		data[1] = strconv.FormatInt(stat.NumModules, 10)
-		data[2] = strconv.FormatInt(stat.NumProbes, 10)
-		table.Append(data)
-
-		totalDriverStats.NumModules += stat.NumModules
-		totalDriverStats.NumProbes += stat.NumProbes
+		archTotals := driverStats{}
+		// Keep keys sorted by semver
+		// (looping directly on the map {key,value} tuples gives wrong sorting sometimes).
+		for _, key := range driverVersions {
+			stat := driverStatsByVersion[key]
+			details.Versions = append(details.Versions, versionStats{
+				Architecture:  arch,
+				DriverVersion: key,
+				driverStats:   stat,
+			})
+			archTotals.NumModules += stat.NumModules
+			archTotals.NumProbes += stat.NumProbes
+		}
+		details.TotalsByArchitecture[arch] = archTotals
+		details.Totals.NumModules += archTotals.NumModules
+		details.Totals.NumProbes += archTotals.NumProbes
 	}
-	data[0] = "TOTALS"
-	data[1] = strconv.FormatInt(totalDriverStats.NumModules, 10)
-	data[2] = strconv.FormatInt(totalDriverStats.NumProbes, 10)
-	table.Append(data)
-	table.Render() // Send output
-
+	opts.Result.SetDetails(details)
 	return nil
 }
+
+func (s statsDetails) Header() []string {
+	return []string{"Architecture", "Version", "Modules", "Probes"}
+}
+
+func (s statsDetails) Rows() [][]string {
+	rows := make([][]string, 0, len(s.Versions)+len(s.TotalsByArchitecture)+1)
+	for i, v := range s.Versions {
+		rows = append(rows, statsRow(v.Architecture.String(), v.DriverVersion, v.driverStats))
+		// Append architecture totals after its last version
+		if i == len(s.Versions)-1 || s.Versions[i+1].Architecture != v.Architecture {
+			rows = append(rows, statsRow(v.Architecture.String(), "TOTALS", s.TotalsByArchitecture[v.Architecture]))
+		}
+	}
+	if len(s.TotalsByArchitecture) > 1 {
+		rows = append(rows, statsRow("", "TOTALS", s.Totals))
+	}
+	return rows
+}
+
+func statsRow(arch, version string, stat driverStats) []string {
+	return []string{
+		arch,
+		version,
+		strconv.FormatInt(stat.NumModules, 10),
+		strconv.FormatInt(stat.NumProbes, 10),
+	}
+}
@@ -15,12 +15,15 @@ limitations under the License.
 package stats
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"testing"
 
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	testutils "github.com/falcosecurity/dbg-go/pkg/utils/test"
 	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
 	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
@@ -198,6 +201,52 @@ func TestStats(t *testing.T) {
 				NumModules: 1,
 			},
 		},
+		"stats 1.0.0+driver x86_64 filtered by multiple distros": {
+			opts: Options{Options: root.Options{
+				RepoRoot:      "./test/",
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver"},
+				DriverName:    "falco",
+				Target: root.Target{
+					Distro: builder.Type(root.JoinPatterns([]string{"centos", "ubuntu"})),
+				},
+			}},
+			expectedStats: driverStats{
+				NumProbes:  2,
+				NumModules: 3,
+			},
+		},
+		"stats 1.0.0+driver x86_64 excluding a distro": {
+			opts: Options{Options: root.Options{
+				RepoRoot:      "./test/",
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver"},
+				DriverName:    "falco",
+				Target: root.Target{
+					ExcludeDistro: []string{"cent.*"},
+				},
+			}},
+			expectedStats: driverStats{
+				NumProbes:  1,
+				NumModules: 2,
+			},
+		},
+		"stats 1.0.0+driver x86_64 excluding a kernel release": {
+			opts: Options{Options: root.Options{
+				RepoRoot:      "./test/",
+				Architecture:  "amd64",
+				DriverVersion: []string{"1.0.0+driver"},
+				DriverName:    "falco",
+				Target: root.Target{
+					Distro:               "centos",
+					ExcludeKernelRelease: []string{"5.10.*"},
+				},
+			}},
+			expectedStats: driverStats{
+				NumProbes:  1,
+				NumModules: 1,
+			},
+		},
 		"stats 1.0.0+driver x86_64 filtered by kernel version": {
 			opts: Options{Options: root.Options{
 				RepoRoot:      "./test/",
@@ -218,11 +267,62 @@ func TestStats(t *testing.T) {
 	statter := NewFileStatter()
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			driverStatsByVersion, err := statter.GetDriverStats(test.opts.Options)
+			driverStatsByVersion, err := statter.GetDriverStats(context.Background(), test.opts.Options)
 			assert.NoError(t, err)
 			assert.Equal(t, test.expectedStats, driverStatsByVersion["1.0.0+driver"])
 		})
 	}
+
+	t.Run("stats rows sorted by driver version", func(t *testing.T) {
+		opts := Options{Options: root.Options{
+			RepoRoot:      "./test/",
+			Architecture:  "amd64",
+			DriverVersion: []string{"10.0.0+driver", "1.0.0+driver", "2.0.0+driver"},
+			DriverName:    "falco",
+			Result:        root.NewResult(),
+		}}
+		err := Run(context.Background(), opts, statter)
+		assert.NoError(t, err)
+		details, ok := opts.Result.Details().(statsDetails)
+		assert.True(t, ok)
+		rows := details.Rows()
+		assert.Len(t, rows, 4)
+		for i, expected := range []string{"1.0.0+driver", "2.0.0+driver", "10.0.0+driver", "TOTALS"} {
+			assert.Equal(t, expected, rows[i][1])
+		}
+	})
+
+	t.Run("stats 1.0.0+driver x86_64 and aarch64 structured output", func(t *testing.T) {
+		opts := Options{Options: root.Options{
+			RepoRoot:      "./test/",
+			Architectures: []kernelrelease.Architecture{"amd64", "arm64"},
+			DriverVersion: []string{"1.0.0+driver"},
+			DriverName:    "falco",
+			Result:        root.NewResult(),
+		}}
+		err := Run(context.Background(), opts, statter)
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		err = root.RenderReport(&buf, root.OutputYAML, root.NewReport("stats", opts.Options, nil))
+		assert.NoError(t, err)
+		var report struct {
+			Details statsDetails `yaml:"details"`
+		}
+		err = yaml.Unmarshal(buf.Bytes(), &report)
+		assert.NoError(t, err)
+		assert.Equal(t, statsDetails{
+			Versions: []versionStats{
+				{Architecture: "amd64", DriverVersion: "1.0.0+driver", driverStats: driverStats{NumProbes: 3, NumModules: 4}},
+				{Architecture: "arm64", DriverVersion: "1.0.0+driver", driverStats: driverStats{}},
+			},
+			TotalsByArchitecture: map[kernelrelease.Architecture]driverStats{
+				"amd64": {NumProbes: 3, NumModules: 4},
+				"arm64": {},
+			},
+			Totals: driverStats{NumProbes: 3, NumModules: 4},
+		}, report.Details)
+	})
 }
 
 func TestStatsS3(t *testing.T) {
@@ -349,7 +449,7 @@ func TestStatsS3(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			dStats, err := statter.GetDriverStats(test.opts.Options)
+			dStats, err := statter.GetDriverStats(context.Background(), test.opts.Options)
 			assert.NoError(t, err)
 			assert.Equal(t, test.expectedStats, dStats)
 		})
@@ -15,10 +15,13 @@ limitations under the License.
 package stats
 
 import (
+	"context"
 	"os"
+	"sync"
 
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	"github.com/falcosecurity/dbg-go/pkg/validate"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
@@ -35,12 +38,19 @@ func (f *fileStatter) Info() string {
 	return "gathering stats for local config files"
 }
 
-func (f *fileStatter) GetDriverStats(opts root.Options) (driverStatsByDriverVersion, error) {
+func (f *fileStatter) GetDriverStats(ctx context.Context, opts root.Options) (driverStatsByDriverVersion, error) {
+	var mu sync.Mutex
 	driverStatsByVersion := make(driverStatsByDriverVersion)
-	err := f.LoopFiltered(opts, "computing stats", "config", func(driverVersion, configPath string) error {
+	err := f.LoopFiltered(ctx, opts, "computing stats", "config", func(_ context.Context, _ kernelrelease.Architecture, driverVersion, configPath string) error {
+		var cStats driverStats
+		err := getConfigStats(&cStats, configPath)
+		// Workers may run concurrently
+		mu.Lock()
 		dStats := driverStatsByVersion[driverVersion]
-		err := getConfigStats(&dStats, configPath)
+		dStats.NumProbes += cStats.NumProbes
+		dStats.NumModules += cStats.NumModules
 		driverStatsByVersion[driverVersion] = dStats
+		mu.Unlock()
 		return err
 	})
 	return driverStatsByVersion, err
@@ -15,10 +15,12 @@ limitations under the License.
 package stats
 
 import (
+	"context"
 	"strings"
 
 	"github.com/falcosecurity/dbg-go/pkg/root"
 	s3utils "github.com/falcosecurity/dbg-go/pkg/utils/s3"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 type s3Statter struct {
@@ -37,9 +39,9 @@ func (f *s3Statter) Info() string {
 	return "gathering stats for remote drivers"
 }
 
-func (s *s3Statter) GetDriverStats(opts root.Options) (driverStatsByDriverVersion, error) {
+func (s *s3Statter) GetDriverStats(ctx context.Context, opts root.Options) (driverStatsByDriverVersion, error) {
 	driverStatsByVersion := make(driverStatsByDriverVersion)
-	err := s.LoopFiltered(opts, "computing stats for S3 bucket "+s3utils.S3Bucket, "key", func(driverVersion, key string) error {
+	err := s.LoopFiltered(ctx, opts, "computing stats for S3 bucket "+s3utils.S3Bucket, "key", func(_ context.Context, _ kernelrelease.Architecture, driverVersion, key string) error {
 		dStats := driverStatsByVersion[driverVersion]
 		if strings.HasSuffix(key, ".ko") {
 			dStats.NumModules++
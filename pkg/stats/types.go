@@ -15,7 +15,10 @@ limitations under the License.
 package stats
 
 import (
+	"context"
+
 	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 type Options struct {
@@ -23,13 +26,25 @@ type Options struct {
 }
 
 type driverStats struct {
-	NumProbes  int64
-	NumModules int64
+	NumProbes  int64 `json:"probes" yaml:"probes"`
+	NumModules int64 `json:"modules" yaml:"modules"`
+}
+
+type versionStats struct {
+	Architecture  kernelrelease.Architecture `json:"architecture" yaml:"architecture"`
+	DriverVersion string                     `json:"driverVersion" yaml:"driverVersion"`
+	driverStats   `yaml:",inline"`
+}
+
+type statsDetails struct {
+	Versions             []versionStats                             `json:"versions" yaml:"versions"`
+	TotalsByArchitecture map[kernelrelease.Architecture]driverStats `json:"totalsByArchitecture" yaml:"totalsByArchitecture"`
+	Totals               driverStats                                `json:"totals" yaml:"totals"`
 }
 
 type driverStatsByDriverVersion map[string]driverStats
 
 type Statter interface {
 	Info() string
-	GetDriverStats(opts root.Options) (driverStatsByDriverVersion, error)
+	GetDriverStats(ctx context.Context, opts root.Options) (driverStatsByDriverVersion, error)
 }
@@ -21,26 +21,44 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
 )
 
 const (
 	S3Bucket             = "falco-distribution"
 	s3Region             = "eu-west-1"
+	driverPrefix         = "driver/"
 	s3DriverNameRegexFmt = `^%s_(?P<Distro>[a-zA-Z-0-9.0-9]*)_(?P<KernelRelease>.*)_(?P<KernelVersion>.*)(\.o|\.ko)`
 )
 
-func (cl *Client) LoopFiltered(opts root.Options,
+func (cl *Client) LoopFiltered(ctx context.Context,
+	opts root.Options,
 	message, tag string,
 	keyProcessor root.RowWorker,
 ) error {
 	s3DriverNameRegex := regexp.MustCompile(fmt.Sprintf(s3DriverNameRegexFmt, opts.DriverName))
+	for _, arch := range opts.Archs() {
+		if err := cl.loopFilteredArch(ctx, opts, arch, s3DriverNameRegex, message, tag, keyProcessor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cl *Client) loopFilteredArch(ctx context.Context,
+	opts root.Options, arch kernelrelease.Architecture,
+	s3DriverNameRegex *regexp.Regexp,
+	message, tag string,
+	keyProcessor root.RowWorker,
+) error {
 	for _, driverVersion := range opts.DriverVersion {
-		prefix := filepath.Join("driver", driverVersion, opts.Architecture.ToNonDeb())
+		prefix := filepath.Join("driver", driverVersion, arch.ToNonDeb())
 		params := &s3.ListObjectsV2Input{
 			Bucket: aws.String(S3Bucket),
 			Prefix: aws.String(prefix),
@@ -54,7 +72,7 @@ func (cl *Client) LoopFiltered(opts root.Options,
 		for p.HasMorePages() {
 			root.Printer.Logger.Debug("fetched a page of objects",
 				root.Printer.Logger.Args("prefix", prefix))
-			page, err := p.NextPage(context.TODO())
+			page, err := p.NextPage(ctx)
 			if err != nil {
 				return err
 			}
@@ -88,14 +106,29 @@ func (cl *Client) LoopFiltered(opts root.Options,
 						}
 					}
 				}
-				root.Printer.Logger.Info(message,
-					root.Printer.Logger.Args(tag, key))
+				entry := root.ResultItem{
+					Action:        message,
+					Architecture:  arch,
+					DriverVersion: driverVersion,
+					Path:          filepath.Join(prefix, key),
+				}
 				if opts.DryRun {
-					root.Printer.Logger.Info("skipping because of dry-run.")
-					return nil
+					// Just enumerate the keys that would be processed
+					root.Printer.Logger.Info("skipping because of dry-run.",
+						root.Printer.Logger.Args(tag, key))
+					opts.Result.Plan(entry)
+					continue
 				}
-				err = keyProcessor(driverVersion, filepath.Join(prefix, key))
-				if err != nil {
+				if err = ctx.Err(); err != nil {
+					// Interrupted; next keys are not even listed
+					opts.Result.Record(entry, err)
+					return err
+				}
+				root.Printer.Logger.Info(message,
+					root.Printer.Logger.Args(tag, key))
+				err = keyProcessor(ctx, arch, driverVersion, entry.Path)
+				opts.Result.Record(entry, err)
+				if err = root.LoopError(err); err != nil {
 					return err
 				}
 			}
@@ -104,30 +137,30 @@ func (cl *Client) LoopFiltered(opts root.Options,
 	return nil
 }
 
-func (cl *Client) HeadDriver(opts root.Options, driverVersion, key string) bool {
+func (cl *Client) HeadDriver(ctx context.Context, opts root.Options, driverVersion, key string) bool {
 	prefix := filepath.Join("driver", driverVersion, opts.Architecture.ToNonDeb())
 	fullKey := filepath.Join(prefix, key)
-	object, _ := cl.HeadObject(context.Background(), &s3.HeadObjectInput{
+	object, _ := cl.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(S3Bucket),
 		Key:    aws.String(fullKey),
 	})
 	return object != nil
 }
 
-func (cl *Client) PutDriver(opts root.Options, driverVersion, path string) error {
+func (cl *Client) PutDriver(ctx context.Context, opts root.Options, driverVersion, path string) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
-	err = cl.putObject(opts, driverVersion, filepath.Base(path), f)
+	err = cl.putObject(ctx, opts, driverVersion, filepath.Base(path), f)
 	_ = f.Close()
 	return err
 }
 
-func (cl *Client) putObject(opts root.Options, driverVersion, key string, reader io.Reader) error {
+func (cl *Client) putObject(ctx context.Context, opts root.Options, driverVersion, key string, reader io.Reader) error {
 	prefix := filepath.Join("driver", driverVersion, opts.Architecture.ToNonDeb())
 	fullKey := filepath.Join(prefix, key)
-	_, err := cl.Client.PutObject(context.Background(), &s3.PutObjectInput{
+	_, err := cl.Client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:               aws.String(S3Bucket),
 		Key:                  aws.String(fullKey),
 		ACL:                  types.ObjectCannedACLPublicRead,
@@ -137,3 +170,30 @@ func (cl *Client) putObject(opts root.Options, driverVersion, key string, reader
 	})
 	return err
 }
+
+// DriverVersions returns the driver versions available under the bucket "driver/" prefix.
+func (cl *Client) DriverVersions(ctx context.Context) ([]string, error) {
+	params := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(S3Bucket),
+		Prefix:    aws.String(driverPrefix),
+		Delimiter: aws.String("/"),
+	}
+	driverVersions := make([]string, 0)
+	p := s3.NewListObjectsV2Paginator(cl, params)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, commonPrefix := range page.CommonPrefixes {
+			if commonPrefix.Prefix == nil {
+				continue
+			}
+			driverVersion := strings.TrimSuffix(strings.TrimPrefix(*commonPrefix.Prefix, driverPrefix), "/")
+			if driverVersion != "" {
+				driverVersions = append(driverVersions, driverVersion)
+			}
+		}
+	}
+	return driverVersions, nil
+}
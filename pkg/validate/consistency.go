@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"gopkg.in/yaml.v3"
+)
+
+// Cross-config consistency rules
+const (
+	ruleKernelInMultipleDistros    = "kernel-in-multiple-distros"
+	ruleDuplicateKernelVersion     = "duplicate-kernelversion"
+	ruleMissingInNextDriverVersion = "missing-in-next-driver-version"
+	ruleSharedKernelUrls           = "shared-kernelurls"
+)
+
+// ConsistencyFinding is a problem spanning multiple configs, found once all of them are validated.
+// Consistency findings are never hard errors.
+type ConsistencyFinding struct {
+	Rule    string   `json:"rule" yaml:"rule"`
+	Message string   `json:"message" yaml:"message"`
+	Configs []string `json:"configs" yaml:"configs"`
+}
+
+type consistencyDetails []ConsistencyFinding
+
+func (c consistencyDetails) Header() []string {
+	return []string{"rule", "message", "configs"}
+}
+
+func (c consistencyDetails) Rows() [][]string {
+	rows := make([][]string, 0, len(c))
+	for _, finding := range c {
+		rows = append(rows, []string{finding.Rule, finding.Message, strings.Join(finding.Configs, ",")})
+	}
+	return rows
+}
+
+// consistencyConfig is a parsed config, as seen by consistency checks.
+type consistencyConfig struct {
+	path          string
+	arch          kernelrelease.Architecture
+	driverVersion string
+	dkYaml        DriverkitYaml
+}
+
+// scope identifies the driver version and architecture folder a config lives in.
+func (c consistencyConfig) scope() string {
+	return c.driverVersion + "/" + c.arch.String()
+}
+
+// loadConsistencyConfigs parses the validated configs; unreadable ones are skipped,
+// since they are already reported by the per config validation.
+func loadConsistencyConfigs(validated []ValidatedConfig) []consistencyConfig {
+	configs := make([]consistencyConfig, 0, len(validated))
+	for _, config := range validated {
+		data, err := os.ReadFile(config.Path)
+		if err != nil {
+			continue
+		}
+		var dkYaml DriverkitYaml
+		if err = yaml.Unmarshal(data, &dkYaml); err != nil {
+			continue
+		}
+		configs = append(configs, consistencyConfig{
+			path:          config.Path,
+			arch:          config.Architecture,
+			driverVersion: config.DriverVersion,
+			dkYaml:        dkYaml,
+		})
+	}
+	return configs
+}
+
+// checkConsistency runs the cross-config checks, within each driver version and architecture,
+// and between subsequent driver versions.
+func checkConsistency(opts root.Options, configs []consistencyConfig) []ConsistencyFinding {
+	var findings []ConsistencyFinding
+	findings = append(findings, checkGrouped(opts, configs, ruleKernelInMultipleDistros,
+		func(c consistencyConfig) (string, string) {
+			return c.dkYaml.KernelRelease, c.dkYaml.Target
+		},
+		func(key string, targets []string) string {
+			return fmt.Sprintf("kernel release %s appears under multiple targets: %s", key, strings.Join(targets, ","))
+		})...)
+	findings = append(findings, checkGrouped(opts, configs, ruleDuplicateKernelVersion,
+		func(c consistencyConfig) (string, string) {
+			return c.dkYaml.Target + " " + c.dkYaml.KernelRelease, c.dkYaml.KernelVersion
+		},
+		func(key string, kernelVersions []string) string {
+			return fmt.Sprintf("%s has configs only differing by kernel version: %s", key, strings.Join(kernelVersions, ","))
+		})...)
+	findings = append(findings, checkGrouped(opts, configs, ruleSharedKernelUrls,
+		func(c consistencyConfig) (string, string) {
+			if len(c.dkYaml.KernelUrls) == 0 {
+				return "", ""
+			}
+			return strings.Join(slices.Sorted(slices.Values(c.dkYaml.KernelUrls)), " "), c.dkYaml.ToName()
+		},
+		func(_ string, names []string) string {
+			return fmt.Sprintf("configs share identical kernelurls: %s", strings.Join(names, ","))
+		})...)
+	findings = append(findings, checkNextDriverVersion(opts, configs)...)
+	return findings
+}
+
+// checkGrouped groups configs of the same driver version and architecture by key;
+// a finding is reported for each group whose configs have more than one distinct value.
+// Configs with an empty key are ignored.
+func checkGrouped(opts root.Options, configs []consistencyConfig, rule string,
+	keyValue func(consistencyConfig) (string, string), message func(key string, values []string) string) []ConsistencyFinding {
+	type group struct {
+		key    string
+		values map[string]struct{}
+		paths  []string
+	}
+	groups := make(map[string]*group)
+	for _, config := range configs {
+		key, value := keyValue(config)
+		if key == "" {
+			continue
+		}
+		groupKey := config.scope() + "\x00" + key
+		g, ok := groups[groupKey]
+		if !ok {
+			g = &group{key: key, values: make(map[string]struct{})}
+			groups[groupKey] = g
+		}
+		g.values[value] = struct{}{}
+		g.paths = append(g.paths, reportPath(opts, config.path))
+	}
+
+	var findings []ConsistencyFinding
+	for _, groupKey := range slices.Sorted(maps.Keys(groups)) {
+		g := groups[groupKey]
+		if len(g.values) < 2 {
+			continue
+		}
+		slices.Sort(g.paths)
+		findings = append(findings, ConsistencyFinding{
+			Rule:    rule,
+			Message: message(g.key, slices.Sorted(maps.Keys(g.values))),
+			Configs: g.paths,
+		})
+	}
+	return findings
+}
+
+// checkNextDriverVersion reports configs of a driver version, that are missing for the next one, for the same architecture.
+func checkNextDriverVersion(opts root.Options, configs []consistencyConfig) []ConsistencyFinding {
+	// arch -> driver version -> config name -> config path
+	names := make(map[kernelrelease.Architecture]map[string]map[string]string)
+	for _, config := range configs {
+		if names[config.arch] == nil {
+			names[config.arch] = make(map[string]map[string]string)
+		}
+		if names[config.arch][config.driverVersion] == nil {
+			names[config.arch][config.driverVersion] = make(map[string]string)
+		}
+		names[config.arch][config.driverVersion][config.dkYaml.ToConfigName()] = config.path
+	}
+
+	var findings []ConsistencyFinding
+	for _, arch := range slices.SortedFunc(maps.Keys(names), func(a, b kernelrelease.Architecture) int {
+		return cmp.Compare(a.String(), b.String())
+	}) {
+		driverVersions := slices.SortedFunc(maps.Keys(names[arch]), root.CompareDriverVersions)
+		for i := 0; i < len(driverVersions)-1; i++ {
+			current, next := names[arch][driverVersions[i]], names[arch][driverVersions[i+1]]
+			for _, name := range slices.Sorted(maps.Keys(current)) {
+				if _, ok := next[name]; ok {
+					continue
+				}
+				findings = append(findings, ConsistencyFinding{
+					Rule: ruleMissingInNextDriverVersion,
+					Message: fmt.Sprintf("config %s of driver version %s is missing for driver version %s",
+						name, driverVersions[i], driverVersions[i+1]),
+					Configs: []string{reportPath(opts, current[name])},
+				})
+			}
+		}
+	}
+	return findings
+}
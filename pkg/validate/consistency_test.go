@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	opts := root.Options{
+		RepoRoot:     "/repo",
+		DriverName:   "falco",
+		Architecture: "amd64",
+	}
+	config := func(driverVersion, target, kernelRelease, kernelVersion string, kernelUrls ...string) consistencyConfig {
+		dkYaml := DriverkitYaml{
+			KernelVersion: kernelVersion,
+			KernelRelease: kernelRelease,
+			Target:        target,
+			Architecture:  "amd64",
+			KernelUrls:    kernelUrls,
+		}
+		return consistencyConfig{
+			path:          root.BuildConfigPath(opts, driverVersion, dkYaml.ToConfigName()),
+			arch:          "amd64",
+			driverVersion: driverVersion,
+			dkYaml:        dkYaml,
+		}
+	}
+	path := func(driverVersion, name string) string {
+		return "driverkit/config/" + driverVersion + "/x86_64/" + name
+	}
+
+	tests := map[string]struct {
+		configs  []consistencyConfig
+		expected []ConsistencyFinding
+	}{
+		"consistent": {
+			configs: []consistencyConfig{
+				config("1.0.0+driver", "centos", "5.10.0", "1", "url1"),
+				config("1.0.0+driver", "centos", "5.11.0", "1", "url2"),
+				config("2.0.0+driver", "centos", "5.10.0", "1", "url1"),
+				config("2.0.0+driver", "centos", "5.11.0", "1", "url2"),
+			},
+		},
+		"kernel in multiple distros": {
+			configs: []consistencyConfig{
+				config("1.0.0+driver", "centos", "5.10.0", "1"),
+				config("1.0.0+driver", "fedora", "5.10.0", "1"),
+			},
+			expected: []ConsistencyFinding{{
+				Rule:    ruleKernelInMultipleDistros,
+				Message: "kernel release 5.10.0 appears under multiple targets: centos,fedora",
+				Configs: []string{path("1.0.0+driver", "centos_5.10.0_1.yaml"), path("1.0.0+driver", "fedora_5.10.0_1.yaml")},
+			}},
+		},
+		"duplicate kernelversion": {
+			configs: []consistencyConfig{
+				config("1.0.0+driver", "centos", "5.10.0", "1"),
+				config("1.0.0+driver", "centos", "5.10.0", "2"),
+			},
+			expected: []ConsistencyFinding{{
+				Rule:    ruleDuplicateKernelVersion,
+				Message: "centos 5.10.0 has configs only differing by kernel version: 1,2",
+				Configs: []string{path("1.0.0+driver", "centos_5.10.0_1.yaml"), path("1.0.0+driver", "centos_5.10.0_2.yaml")},
+			}},
+		},
+		"shared kernelurls": {
+			configs: []consistencyConfig{
+				config("1.0.0+driver", "centos", "5.10.0", "1", "url1", "url2"),
+				config("1.0.0+driver", "centos", "5.11.0", "1", "url2", "url1"),
+			},
+			expected: []ConsistencyFinding{{
+				Rule:    ruleSharedKernelUrls,
+				Message: "configs share identical kernelurls: centos_5.10.0_1,centos_5.11.0_1",
+				Configs: []string{path("1.0.0+driver", "centos_5.10.0_1.yaml"), path("1.0.0+driver", "centos_5.11.0_1.yaml")},
+			}},
+		},
+		"missing in next driver version": {
+			configs: []consistencyConfig{
+				config("10.0.0+driver", "centos", "5.10.0", "1"),
+				config("2.0.0+driver", "centos", "5.10.0", "1"),
+				config("2.0.0+driver", "centos", "5.11.0", "1"),
+			},
+			expected: []ConsistencyFinding{{
+				Rule:    ruleMissingInNextDriverVersion,
+				Message: "config centos_5.11.0_1.yaml of driver version 2.0.0+driver is missing for driver version 10.0.0+driver",
+				Configs: []string{path("2.0.0+driver", "centos_5.11.0_1.yaml")},
+			}},
+		},
+		"different driver versions are not compared": {
+			configs: []consistencyConfig{
+				config("1.0.0+driver", "centos", "5.10.0", "1"),
+				config("2.0.0+driver", "centos", "5.10.0", "1"),
+				config("2.0.0+driver", "fedora", "5.11.0", "1"),
+				config("1.0.0+driver", "fedora", "5.11.0", "1"),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, checkConsistency(opts, test.configs))
+		})
+	}
+}
+
+func TestValidateConsistency(t *testing.T) {
+	opts := root.Options{
+		RepoRoot:      t.TempDir(),
+		DriverName:    "falco",
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver", "2.0.0+driver"},
+	}
+	writeConfig := func(driverVersion string, dkYaml DriverkitYaml) {
+		dkYaml.FillOutputs(driverVersion, opts)
+		configPath := root.BuildConfigPath(opts, driverVersion, dkYaml.ToConfigName())
+		assert.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0o755))
+		cleanup, err := generateConfigFile(dkYaml, configPath)
+		assert.NoError(t, err)
+		t.Cleanup(cleanup)
+	}
+	writeConfig("1.0.0+driver", DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "centos", Architecture: "amd64"})
+	writeConfig("1.0.0+driver", DriverkitYaml{KernelVersion: "1", KernelRelease: "5.11.0", Target: "centos", Architecture: "amd64"})
+	writeConfig("2.0.0+driver", DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "centos", Architecture: "amd64"})
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	validateOpts := Options{
+		Options:     opts,
+		Reports:     []ReportSpec{{Format: ReportJSON, Path: reportPath}},
+		Consistency: true,
+	}
+	validateOpts.Result = root.NewResult()
+	// Consistency findings are not errors
+	assert.NoError(t, Run(context.Background(), validateOpts))
+
+	data, err := os.ReadFile(reportPath)
+	assert.NoError(t, err)
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, reportSummary{Configs: 3}, report.Summary)
+	assert.Equal(t, []ConsistencyFinding{{
+		Rule:    ruleMissingInNextDriverVersion,
+		Message: "config centos_5.11.0_1.yaml of driver version 1.0.0+driver is missing for driver version 2.0.0+driver",
+		Configs: []string{"driverkit/config/1.0.0+driver/x86_64/centos_5.11.0_1.yaml"},
+	}}, report.Consistency)
+}
@@ -14,7 +14,27 @@ limitations under the License.
 
 package validate
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ruleWrongConfigName           = "wrong-config-name"
+	ruleWrongArch                 = "wrong-arch"
+	ruleWrongOutputProbeName      = "wrong-output-probe-name"
+	ruleWrongOutputProbeArch      = "wrong-output-probe-arch"
+	ruleWrongOutputModuleName     = "wrong-output-module-name"
+	ruleWrongOutputModuleArch     = "wrong-output-module-arch"
+	ruleKernelConfigDataNotBase64 = "kernelconfigdata-not-base64"
+	ruleOverlayDrift              = "overlay-drift"
+	ruleOutputOnUnsupportedKernel = "output-on-unsupported-kernel"
+	ruleSchema                    = "schema"
+	ruleMissingField              = "missing-field"
+	ruleUnknownTarget             = "unknown-target"
+	ruleUnreachableKernelUrls     = "unreachable-kernelurls"
+	ruleTooFewKernelUrls          = "too-few-kernelurls"
+)
 
 type WrongConfigNameErr struct {
 	configName         string
@@ -25,6 +45,10 @@ func (w *WrongConfigNameErr) Error() string {
 	return fmt.Sprintf("config filename is wrong (%s); should be %s", w.configName, w.expectedConfigName)
 }
 
+func (w *WrongConfigNameErr) Rule() string {
+	return ruleWrongConfigName
+}
+
 type WrongArchInConfigErr struct {
 	configPath string
 	arch       string
@@ -34,6 +58,10 @@ func (w *WrongArchInConfigErr) Error() string {
 	return fmt.Sprintf("wrong architecture in config file %s: %s", w.configPath, w.arch)
 }
 
+func (w *WrongArchInConfigErr) Rule() string {
+	return ruleWrongArch
+}
+
 type WrongOutputProbeNameErr struct {
 	outputProbeName         string
 	expectedOutputProbeName string
@@ -43,6 +71,10 @@ func (w *WrongOutputProbeNameErr) Error() string {
 	return fmt.Sprintf("output probe filename is wrong (%s); expected: %s.o", w.outputProbeName, w.expectedOutputProbeName)
 }
 
+func (w *WrongOutputProbeNameErr) Rule() string {
+	return ruleWrongOutputProbeName
+}
+
 type WrongOutputProbeArchErr struct {
 	probe string
 	arch  string
@@ -52,6 +84,10 @@ func (w *WrongOutputProbeArchErr) Error() string {
 	return fmt.Sprintf("output probe filename has wrong architecture in its path (%s); expected %s", w.probe, w.arch)
 }
 
+func (w *WrongOutputProbeArchErr) Rule() string {
+	return ruleWrongOutputProbeArch
+}
+
 type WrongOutputModuleNameErr struct {
 	outputModuleName         string
 	expectedOutputModuleName string
@@ -61,6 +97,10 @@ func (w *WrongOutputModuleNameErr) Error() string {
 	return fmt.Sprintf("output module filename is wrong (%s); expected: %s.o", w.outputModuleName, w.expectedOutputModuleName)
 }
 
+func (w *WrongOutputModuleNameErr) Rule() string {
+	return ruleWrongOutputModuleName
+}
+
 type WrongOutputModuleArchErr struct {
 	module string
 	arch   string
@@ -70,8 +110,132 @@ func (w *WrongOutputModuleArchErr) Error() string {
 	return fmt.Sprintf("output module filename has wrong architecture in its path (%s); expected %s", w.module, w.arch)
 }
 
+func (w *WrongOutputModuleArchErr) Rule() string {
+	return ruleWrongOutputModuleArch
+}
+
 type KernelConfigDataNotBase64Err struct{}
 
 func (k *KernelConfigDataNotBase64Err) Error() string {
 	return fmt.Sprintf("kernelconfigdata must be a base64 encoded string")
 }
+
+func (k *KernelConfigDataNotBase64Err) Rule() string {
+	return ruleKernelConfigDataNotBase64
+}
+
+type OverlayDriftErr struct {
+	configPath string
+	fields     []string
+}
+
+func (o *OverlayDriftErr) Error() string {
+	return fmt.Sprintf("config %s drifted from overlays; fields: %s", o.configPath, strings.Join(o.fields, ","))
+}
+
+func (o *OverlayDriftErr) Rule() string {
+	return ruleOverlayDrift
+}
+
+// OutputOnUnsupportedKernelWarn is a warning: the output is set for a kernel release not supporting it.
+type OutputOnUnsupportedKernelWarn struct {
+	configPath    string
+	kernelRelease string
+	output        string
+}
+
+func (o *OutputOnUnsupportedKernelWarn) Error() string {
+	return fmt.Sprintf("output %s set on an unsupported kernel release (%s) in config %s", o.output, o.kernelRelease, o.configPath)
+}
+
+func (o *OutputOnUnsupportedKernelWarn) Rule() string {
+	return ruleOutputOnUnsupportedKernel
+}
+
+// SchemaErr is returned, in strict mode, when a config does not match the DriverkitYaml schema, eg: it has unknown fields.
+type SchemaErr struct {
+	configPath string
+	errs       []string
+}
+
+func (s *SchemaErr) Error() string {
+	return fmt.Sprintf("config %s does not match the schema: %s", s.configPath, strings.Join(s.errs, "; "))
+}
+
+func (s *SchemaErr) Rule() string {
+	return ruleSchema
+}
+
+type MissingFieldErr struct {
+	configPath string
+	field      string
+	target     string
+}
+
+func (m *MissingFieldErr) Error() string {
+	if m.target != "" {
+		return fmt.Sprintf("config %s is missing field %s, required by target %s", m.configPath, m.field, m.target)
+	}
+	return fmt.Sprintf("config %s is missing required field %s", m.configPath, m.field)
+}
+
+func (m *MissingFieldErr) Rule() string {
+	return ruleMissingField
+}
+
+type UnknownTargetErr struct {
+	configPath string
+	target     string
+}
+
+func (u *UnknownTargetErr) Error() string {
+	return fmt.Sprintf("config %s has a target unknown to driverkit: %s", u.configPath, u.target)
+}
+
+func (u *UnknownTargetErr) Rule() string {
+	return ruleUnknownTarget
+}
+
+type UnreachableKernelUrlsErr struct {
+	configPath string
+	urls       []string
+}
+
+func (u *UnreachableKernelUrlsErr) Error() string {
+	return fmt.Sprintf("config %s has no reachable kernelurls: %s", u.configPath, strings.Join(u.urls, ", "))
+}
+
+func (u *UnreachableKernelUrlsErr) Rule() string {
+	return ruleUnreachableKernelUrls
+}
+
+type TooFewKernelUrlsErr struct {
+	configPath string
+	target     string
+	reachable  int
+	minimum    int
+	urls       []string
+}
+
+func (t *TooFewKernelUrlsErr) Error() string {
+	return fmt.Sprintf("config %s has %d reachable kernelurls, target %s requires at least %d; unreachable: %s",
+		t.configPath, t.reachable, t.target, t.minimum, strings.Join(t.urls, ", "))
+}
+
+func (t *TooFewKernelUrlsErr) Rule() string {
+	return ruleTooFewKernelUrls
+}
+
+// UnreachableKernelUrlsWarn is returned when some kernelurls are unreachable, but enough of them are left to build the drivers.
+type UnreachableKernelUrlsWarn struct {
+	configPath string
+	urls       []string
+}
+
+func (u *UnreachableKernelUrlsWarn) Error() string {
+	return fmt.Sprintf("config %s has unreachable kernelurls: %s", u.configPath, strings.Join(u.urls, ", "))
+}
+
+func (u *UnreachableKernelUrlsWarn) Rule() string {
+	return ruleUnreachableKernelUrls
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// ruleErr is implemented by validation errors and warnings, to be identified in reports.
+type ruleErr interface {
+	error
+	Rule() string
+}
+
+// ruleInvalidConfig identifies errors that are not specific to a validation rule, eg: unparsable configs.
+const ruleInvalidConfig = "invalid-config"
+
+// ruleDescriptions describe each validation rule, in reports.
+var ruleDescriptions = map[string]string{
+	ruleInvalidConfig:             "Config must be a readable driverkit yaml",
+	ruleWrongConfigName:           "Config filename must match its target, kernel release and kernel version",
+	ruleWrongArch:                 "Config architecture must match the architecture folder it lives in",
+	ruleWrongOutputProbeName:      "Output probe filename must match the config",
+	ruleWrongOutputProbeArch:      "Output probe path must contain the config architecture",
+	ruleWrongOutputModuleName:     "Output module filename must match the config",
+	ruleWrongOutputModuleArch:     "Output module path must contain the config architecture",
+	ruleKernelConfigDataNotBase64: "Kernelconfigdata must be base64 encoded",
+	ruleOverlayDrift:              "Config must match the overlays applying to it",
+	ruleOutputOnUnsupportedKernel: "Outputs should only be set for kernels supporting them",
+	ruleSchema:                    "Config must match the driverkit config schema, eg: without unknown fields",
+	ruleMissingField:              "Config must set the fields required by its target",
+	ruleUnknownTarget:             "Config target must be known to driverkit",
+	ruleUnreachableKernelUrls:     "Config kernelurls should be reachable",
+	ruleTooFewKernelUrls:          "Config must have as many reachable kernelurls as required by its target",
+
+	ruleKernelInMultipleDistros:    "A kernel release should only appear under a single target",
+	ruleDuplicateKernelVersion:     "Configs should not only differ by kernel version",
+	ruleMissingInNextDriverVersion: "Configs of a driver version should also exist for the next one",
+	ruleSharedKernelUrls:           "Configs should not share identical kernelurls",
+}
+
+func ruleOf(err error) string {
+	var rErr ruleErr
+	if errors.As(err, &rErr) {
+		return rErr.Rule()
+	}
+	return ruleInvalidConfig
+}
+
+// Finding is a single problem found while validating a config.
+type Finding struct {
+	Rule     string   `json:"rule" yaml:"rule"`
+	Severity Severity `json:"severity" yaml:"severity"`
+	Message  string   `json:"message" yaml:"message"`
+}
+
+// configProblems are the errors and warnings found while validating a config.
+type configProblems struct {
+	errs     []error
+	warnings []error
+}
+
+func (p *configProblems) addError(err error) {
+	p.errs = append(p.errs, err)
+}
+
+func (p *configProblems) addWarning(err error) {
+	p.warnings = append(p.warnings, err)
+}
+
+// err returns all the errors joined, or nil if the config is valid.
+func (p *configProblems) err() error {
+	return errors.Join(p.errs...)
+}
+
+func (p *configProblems) findings() []Finding {
+	findings := make([]Finding, 0, len(p.errs)+len(p.warnings))
+	for _, err := range p.errs {
+		findings = append(findings, Finding{Rule: ruleOf(err), Severity: SeverityError, Message: err.Error()})
+	}
+	for _, warning := range p.warnings {
+		findings = append(findings, Finding{Rule: ruleOf(warning), Severity: SeverityWarning, Message: warning.Error()})
+	}
+	return findings
+}
+
+// ValidatedConfig is the outcome of the validation of a single config.
+type ValidatedConfig struct {
+	Path          string                     `json:"path" yaml:"path"`
+	Architecture  kernelrelease.Architecture `json:"architecture" yaml:"architecture"`
+	DriverVersion string                     `json:"driverVersion" yaml:"driverVersion"`
+	Findings      []Finding                  `json:"findings,omitempty" yaml:"findings,omitempty"`
+	// Fixed is set when the config was fixed, or would be in dry-run mode; Findings are the ones left afterwards.
+	Fixed bool `json:"fixed,omitempty" yaml:"fixed,omitempty"`
+}
+
+func (v ValidatedConfig) count(severity Severity) int {
+	n := 0
+	for _, finding := range v.Findings {
+		if finding.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// findingsCollector collects the outcome of each validated config; it is safe for concurrent use.
+type findingsCollector struct {
+	mu      sync.Mutex
+	configs []ValidatedConfig
+}
+
+func (c *findingsCollector) add(config ValidatedConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.configs = append(c.configs, config)
+}
+
+// validated returns the validated configs, sorted so that reports are always rendered the same way.
+func (c *findingsCollector) validated() []ValidatedConfig {
+	c.mu.Lock()
+	configs := slices.Clone(c.configs)
+	c.mu.Unlock()
+	slices.SortFunc(configs, func(a, b ValidatedConfig) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return configs
+}
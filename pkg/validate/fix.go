@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+const actionFixing = "fixing"
+
+// hasProblem returns whether an error of type T was found.
+func hasProblem[T error](p *configProblems) bool {
+	return slices.ContainsFunc(p.errs, func(err error) bool {
+		var target T
+		return errors.As(err, &target)
+	})
+}
+
+// fixable returns whether any of the errors can be fixed automatically;
+// configs that cannot even be parsed are never fixable.
+func (p *configProblems) fixable() bool {
+	return hasProblem[*WrongConfigNameErr](p) ||
+		hasProblem[*WrongArchInConfigErr](p) ||
+		hasProblem[*WrongOutputProbeNameErr](p) ||
+		hasProblem[*WrongOutputProbeArchErr](p) ||
+		hasProblem[*WrongOutputModuleNameErr](p) ||
+		hasProblem[*WrongOutputModuleArchErr](p)
+}
+
+// fixConfig fixes the mechanical errors of a config: its architecture and wrong output paths are rewritten,
+// and it is renamed after its content. The fixed config is validated again; in dry-run mode,
+// nothing is touched, but the fixed content is validated anyway.
+// It returns the fixed config path and the problems left; on error, the original ones.
+func fixConfig(opts Options, driverVersion, configPath string, problems *configProblems) (string, *configProblems, error) {
+	entry := root.ResultItem{
+		Action:        actionFixing,
+		Architecture:  opts.Architecture,
+		DriverVersion: driverVersion,
+		Path:          configPath,
+	}
+	configData, err := os.ReadFile(configPath)
+	if err != nil {
+		opts.Result.Record(entry, err)
+		return configPath, problems, err
+	}
+	var dkYaml DriverkitYaml
+	if err = yaml.Unmarshal(configData, &dkYaml); err != nil {
+		opts.Result.Record(entry, err)
+		return configPath, problems, err
+	}
+
+	fixedData := configData
+	if hasProblem[*WrongArchInConfigErr](problems) ||
+		hasProblem[*WrongOutputProbeNameErr](problems) || hasProblem[*WrongOutputProbeArchErr](problems) ||
+		hasProblem[*WrongOutputModuleNameErr](problems) || hasProblem[*WrongOutputModuleArchErr](problems) {
+		dkYaml.Architecture = opts.Architecture.String()
+		// Only outputs that are set are fixed: unset ones might have been disabled on purpose
+		outputPath := dkYaml.configOutputPath(driverVersion, opts.Options)
+		if hasProblem[*WrongOutputProbeNameErr](problems) || hasProblem[*WrongOutputProbeArchErr](problems) {
+			dkYaml.Output.Probe = outputPath + ".o"
+		}
+		if hasProblem[*WrongOutputModuleNameErr](problems) || hasProblem[*WrongOutputModuleArchErr](problems) {
+			dkYaml.Output.Module = outputPath + ".ko"
+		}
+		yamlData, err := yaml.Marshal(&dkYaml)
+		if err != nil {
+			opts.Result.Record(entry, err)
+			return configPath, problems, err
+		}
+		fixedData = append(LeadingComments(configData), yamlData...)
+	}
+
+	// Configs are named after their content
+	fixedPath := filepath.Join(filepath.Dir(configPath), dkYaml.ToConfigName())
+	entry.Path = fixedPath
+	if fixedPath != configPath {
+		entry.Source = configPath
+		if _, err = os.Stat(fixedPath); err == nil {
+			err = fmt.Errorf("cannot rename %s: %s already exists", configPath, fixedPath)
+			opts.Result.Record(entry, err)
+			return configPath, problems, err
+		}
+	}
+
+	root.Printer.Logger.Info("fixing config",
+		root.Printer.Logger.Args("config", configPath, "to", fixedPath))
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(configData)),
+		B:        difflib.SplitLines(string(fixedData)),
+		FromFile: configPath,
+		ToFile:   fixedPath,
+		Context:  3,
+	})
+	if err == nil && diff == "" {
+		diff = fmt.Sprintf("rename %s => %s\n", configPath, fixedPath)
+	}
+	root.Printer.DefaultText.Print(diff)
+
+	if opts.DryRun {
+		root.Printer.Logger.Info("skipping because of dry-run.",
+			root.Printer.Logger.Args("config", configPath))
+		opts.Result.Plan(entry)
+		return fixedPath, checkConfigData(fixedPath, fixedData, opts, driverVersion), nil
+	}
+
+	if fixedPath == configPath {
+		err = root.WriteFileAtomic(fixedPath, fixedData, 0o644)
+	} else {
+		// Another config might be renamed to the same name concurrently: never overwrite it
+		err = root.CreateFileAtomic(fixedPath, fixedData, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			err = fmt.Errorf("cannot rename %s: %s already exists", configPath, fixedPath)
+		}
+		if err == nil {
+			err = os.Remove(configPath)
+		}
+	}
+	opts.Result.Record(entry, err)
+	if err != nil {
+		return configPath, problems, err
+	}
+	return fixedPath, checkConfig(fixedPath, opts, driverVersion), nil
+}
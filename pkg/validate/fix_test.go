@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateFix(t *testing.T) {
+	opts := root.Options{
+		RepoRoot:      t.TempDir(),
+		DriverName:    "falco",
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+	}
+	configPath := func(name string) string {
+		return root.BuildConfigPath(opts, opts.DriverVersion[0], name)
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Dir(configPath("")), 0o755))
+	validConfig := func(kernelRelease string) DriverkitYaml {
+		dkYaml := DriverkitYaml{KernelVersion: "1", KernelRelease: kernelRelease, Target: "centos", Architecture: "amd64"}
+		dkYaml.FillOutputs(opts.DriverVersion[0], opts)
+		return dkYaml
+	}
+	writeConfig := func(name string, dkYaml DriverkitYaml, header string) {
+		data, err := yaml.Marshal(&dkYaml)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(configPath(name), append([]byte(header), data...), 0o644))
+	}
+
+	// Wrong name, with a leading comment to be kept
+	writeConfig("centos_5.10.0_2.yaml", validConfig("5.10.0"), "# Hand written note\n")
+	// Wrong architecture
+	wrongArch := validConfig("5.11.0")
+	wrongArch.Architecture = "arm64"
+	writeConfig("centos_5.11.0_1.yaml", wrongArch, "")
+	// Wrong outputs
+	wrongOutputs := validConfig("5.12.0")
+	wrongOutputs.Output.Probe = "output/1.0.0+driver/x86_64/wrong.o"
+	wrongOutputs.Output.Module = "output/1.0.0+driver/aarch64/falco_centos_5.12.0_1.ko"
+	writeConfig("centos_5.12.0_1.yaml", wrongOutputs, "")
+	// Renaming would overwrite an existing config
+	writeConfig("centos_5.13.0_1.yaml", validConfig("5.13.0"), "")
+	writeConfig("centos_5.13.0_9.yaml", validConfig("5.13.0"), "")
+	// Not fixable
+	notBase64 := validConfig("5.14.0")
+	notBase64.KernelConfigData = "not base64!"
+	writeConfig("centos_5.14.0_1.yaml", notBase64, "")
+
+	snapshot := func() map[string]string {
+		files, err := filepath.Glob(configPath("*.yaml"))
+		assert.NoError(t, err)
+		contents := make(map[string]string, len(files))
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			contents[filepath.Base(file)] = string(data)
+		}
+		return contents
+	}
+	before := snapshot()
+
+	validateOpts := Options{Options: opts, KeepGoing: true, Fix: true}
+	validateOpts.DryRun = true
+	validateOpts.Result = root.NewResult()
+	assert.Error(t, Run(context.Background(), validateOpts))
+	// Nothing was touched, but fixes are planned
+	assert.Equal(t, before, snapshot())
+	planned := 0
+	for _, item := range validateOpts.Result.Items() {
+		if item.Outcome == root.OutcomePlanned {
+			assert.Equal(t, actionFixing, item.Action)
+			planned++
+		}
+	}
+	assert.Equal(t, 3, planned)
+
+	validateOpts.DryRun = false
+	validateOpts.Result = root.NewResult()
+	err := Run(context.Background(), validateOpts)
+	// Only the conflicting and the not fixable configs are left invalid
+	assert.ErrorContains(t, err, "2 of 6 configs are invalid")
+
+	after := snapshot()
+	assert.ElementsMatch(t, []string{
+		"centos_5.10.0_1.yaml",
+		"centos_5.11.0_1.yaml",
+		"centos_5.12.0_1.yaml",
+		"centos_5.13.0_1.yaml",
+		"centos_5.13.0_9.yaml",
+		"centos_5.14.0_1.yaml",
+	}, slices.Collect(maps.Keys(after)))
+	assert.True(t, strings.HasPrefix(after["centos_5.10.0_1.yaml"], "# Hand written note\n"))
+	assert.Equal(t, before["centos_5.13.0_9.yaml"], after["centos_5.13.0_9.yaml"])
+	assert.Equal(t, before["centos_5.14.0_1.yaml"], after["centos_5.14.0_1.yaml"])
+	for _, name := range []string{"centos_5.10.0_1.yaml", "centos_5.11.0_1.yaml", "centos_5.12.0_1.yaml"} {
+		assert.NoError(t, validateConfig(configPath(name), Options{Options: opts}, opts.DriverVersion[0]), name)
+	}
+
+	// Once fixed, there is nothing left to be fixed
+	validateOpts.Result = root.NewResult()
+	assert.Error(t, Run(context.Background(), validateOpts))
+	assert.Equal(t, after, snapshot())
+}
+
+func TestValidateFixConcurrentRenames(t *testing.T) {
+	opts := root.Options{
+		RepoRoot:      t.TempDir(),
+		DriverName:    "falco",
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+		Jobs:          4,
+	}
+	configPath := func(name string) string {
+		return root.BuildConfigPath(opts, opts.DriverVersion[0], name)
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Dir(configPath("")), 0o755))
+	// Both configs are renamed to centos_5.10.0_1.yaml
+	dkYaml := DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "centos", Architecture: "amd64"}
+	dkYaml.FillOutputs(opts.DriverVersion[0], opts)
+	data, err := yaml.Marshal(&dkYaml)
+	assert.NoError(t, err)
+	for _, name := range []string{"centos_5.10.0_2.yaml", "centos_5.10.0_3.yaml"} {
+		assert.NoError(t, os.WriteFile(configPath(name), append([]byte("# "+name+"\n"), data...), 0o644))
+	}
+
+	validateOpts := Options{Options: opts, KeepGoing: true, Fix: true}
+	validateOpts.Result = root.NewResult()
+	assert.ErrorContains(t, Run(context.Background(), validateOpts), "1 of 2 configs are invalid")
+
+	// One config was renamed, the other one was left untouched
+	files, err := filepath.Glob(configPath("*.yaml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Contains(t, files, configPath("centos_5.10.0_1.yaml"))
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OverlaysFileName is the overlays file searched for in the driverkit folder of the repo root.
+const OverlaysFileName = "overlays.yaml"
+
+// Overlay holds fields that kernel-crawler does not provide, to be applied on top of the configs
+// whose target matches Distro and whose kernel release matches KernelRelease (both regexes).
+type Overlay struct {
+	Distro        string `yaml:"distro"`
+	KernelRelease string `yaml:"kernelrelease,omitempty"`
+	// KernelConfigData, if set, replaces the config kernelconfigdata.
+	KernelConfigData string `yaml:"kernelconfigdata,omitempty"`
+	// KernelUrls are added to the config kernelurls.
+	KernelUrls    []string `yaml:"kernelurls,omitempty"`
+	DisableModule bool     `yaml:"disablemodule,omitempty"`
+	DisableProbe  bool     `yaml:"disableprobe,omitempty"`
+
+	distroRegex        *regexp.Regexp
+	kernelReleaseRegex *regexp.Regexp
+}
+
+// Overlays are applied in order; therefore, when multiple overlays set the kernelconfigdata, the last one wins.
+type Overlays []Overlay
+
+type overlaysFile struct {
+	Overlays Overlays `yaml:"overlays"`
+}
+
+// LoadOverlays loads the overlays file; an explicitly passed path must exist,
+// otherwise the overlays file is searched for in the repo root driverkit folder.
+// No overlays are returned when no overlays file is found.
+func LoadOverlays(explicitPath, repoRoot string) (Overlays, error) {
+	path := explicitPath
+	if path == "" {
+		path = filepath.Join(repoRoot, "driverkit", OverlaysFileName)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if explicitPath == "" && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	overlays, err := ParseOverlays(data)
+	if err != nil {
+		return nil, fmt.Errorf("overlays file %s: %w", path, err)
+	}
+	return overlays, nil
+}
+
+func ParseOverlays(data []byte) (Overlays, error) {
+	var file overlaysFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Overlays {
+		overlay := &file.Overlays[i]
+		if overlay.Distro == "" {
+			return nil, fmt.Errorf("overlay %d: distro is required", i)
+		}
+		var err error
+		if overlay.distroRegex, err = regexp.Compile(overlay.Distro); err != nil {
+			return nil, fmt.Errorf("overlay %d: invalid distro: %w", i, err)
+		}
+		if overlay.kernelReleaseRegex, err = regexp.Compile(overlay.KernelRelease); err != nil {
+			return nil, fmt.Errorf("overlay %d: invalid kernelrelease: %w", i, err)
+		}
+		if overlay.KernelConfigData != "" && !isBase64(overlay.KernelConfigData) {
+			return nil, fmt.Errorf("overlay %d: %w", i, &KernelConfigDataNotBase64Err{})
+		}
+	}
+	return file.Overlays, nil
+}
+
+func (o Overlay) matches(dkYaml *DriverkitYaml) bool {
+	return o.distroRegex.MatchString(dkYaml.Target) && o.kernelReleaseRegex.MatchString(dkYaml.KernelRelease)
+}
+
+// Apply applies all the matching overlays to the config; its outputs must be already filled.
+// Applying overlays is idempotent.
+func (o Overlays) Apply(dkYaml *DriverkitYaml) {
+	for _, overlay := range o {
+		if !overlay.matches(dkYaml) {
+			continue
+		}
+		if overlay.KernelConfigData != "" {
+			dkYaml.KernelConfigData = overlay.KernelConfigData
+		}
+		for _, url := range overlay.KernelUrls {
+			if !slices.Contains(dkYaml.KernelUrls, url) {
+				dkYaml.KernelUrls = append(dkYaml.KernelUrls, url)
+			}
+		}
+		if overlay.DisableModule {
+			dkYaml.Output.Module = ""
+		}
+		if overlay.DisableProbe {
+			dkYaml.Output.Probe = ""
+		}
+	}
+	// Keep the same sorting used for generated configs
+	slices.Sort(dkYaml.KernelUrls)
+}
+
+// drift returns the config fields that differ from the ones expected after applying the overlays.
+func (o Overlays) drift(dkYaml DriverkitYaml) []string {
+	expected := dkYaml
+	expected.KernelUrls = slices.Clone(dkYaml.KernelUrls)
+	o.Apply(&expected)
+
+	var fields []string
+	if expected.KernelConfigData != dkYaml.KernelConfigData {
+		fields = append(fields, "kernelconfigdata")
+	}
+	// Only missing urls are a drift; sorting is not checked
+	for _, url := range expected.KernelUrls {
+		if !slices.Contains(dkYaml.KernelUrls, url) {
+			fields = append(fields, "kernelurls")
+			break
+		}
+	}
+	if expected.Output.Module != dkYaml.Output.Module {
+		fields = append(fields, "output.module")
+	}
+	if expected.Output.Probe != dkYaml.Output.Probe {
+		fields = append(fields, "output.probe")
+	}
+	return fields
+}
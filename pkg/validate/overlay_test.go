@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testOverlays = `
+overlays:
+  - distro: ^centos$
+    kernelrelease: ^5\.14\.
+    kernelconfigdata: Q09ORklHX1RFU1Q9eQo=
+    kernelurls:
+      - http://internal/kernel-devel.rpm
+  - distro: ^ubuntu-
+    disableprobe: true
+`
+
+func TestParseOverlays(t *testing.T) {
+	tests := map[string]struct {
+		data          string
+		expectError   bool
+		expectedCount int
+	}{
+		"valid overlays": {
+			data:          testOverlays,
+			expectedCount: 2,
+		},
+		"empty overlays": {
+			data: "overlays: []",
+		},
+		"missing distro": {
+			data:        "overlays:\n  - kernelrelease: ^5\\.",
+			expectError: true,
+		},
+		"invalid distro regex": {
+			data:        "overlays:\n  - distro: \"(\"",
+			expectError: true,
+		},
+		"invalid kernelrelease regex": {
+			data:        "overlays:\n  - distro: centos\n    kernelrelease: \"[\"",
+			expectError: true,
+		},
+		"kernelconfigdata not base64": {
+			data:        "overlays:\n  - distro: centos\n    kernelconfigdata: \"&&&&\"",
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			overlays, err := ParseOverlays([]byte(test.data))
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, overlays, test.expectedCount)
+		})
+	}
+}
+
+func TestOverlaysApply(t *testing.T) {
+	overlays, err := ParseOverlays([]byte(testOverlays))
+	assert.NoError(t, err)
+
+	outputs := DriverkitYamlOutputs{Module: "output/falco.ko", Probe: "output/falco.o"}
+	tests := map[string]struct {
+		dkYaml   DriverkitYaml
+		expected DriverkitYaml
+	}{
+		"matching distro and kernel release": {
+			dkYaml: DriverkitYaml{Target: "centos", KernelRelease: "5.14.0-325.el9.x86_64", Output: outputs, KernelUrls: []string{"http://centos/kernel-devel.rpm"}},
+			expected: DriverkitYaml{
+				Target:           "centos",
+				KernelRelease:    "5.14.0-325.el9.x86_64",
+				Output:           outputs,
+				KernelUrls:       []string{"http://centos/kernel-devel.rpm", "http://internal/kernel-devel.rpm"},
+				KernelConfigData: "Q09ORklHX1RFU1Q9eQo=",
+			},
+		},
+		"matching distro only": {
+			dkYaml:   DriverkitYaml{Target: "centos", KernelRelease: "4.18.0-477.el8.x86_64", Output: outputs},
+			expected: DriverkitYaml{Target: "centos", KernelRelease: "4.18.0-477.el8.x86_64", Output: outputs},
+		},
+		"disabled probe": {
+			dkYaml:   DriverkitYaml{Target: "ubuntu-aws", KernelRelease: "5.15.0-1040-aws", Output: outputs},
+			expected: DriverkitYaml{Target: "ubuntu-aws", KernelRelease: "5.15.0-1040-aws", Output: DriverkitYamlOutputs{Module: outputs.Module}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dkYaml := test.dkYaml
+			overlays.Apply(&dkYaml)
+			assert.Equal(t, test.expected, dkYaml)
+			assert.Empty(t, overlays.drift(dkYaml))
+
+			// Idempotent
+			overlays.Apply(&dkYaml)
+			assert.Equal(t, test.expected, dkYaml)
+		})
+	}
+}
+
+func TestOverlaysDrift(t *testing.T) {
+	overlays, err := ParseOverlays([]byte(testOverlays))
+	assert.NoError(t, err)
+
+	drifted := DriverkitYaml{
+		Target:        "centos",
+		KernelRelease: "5.14.0-325.el9.x86_64",
+		KernelUrls:    []string{"http://centos/kernel-devel.rpm"},
+	}
+	assert.Equal(t, []string{"kernelconfigdata", "kernelurls"}, overlays.drift(drifted))
+
+	drifted = DriverkitYaml{
+		Target:        "ubuntu-generic",
+		KernelRelease: "5.15.0-76-generic",
+		Output:        DriverkitYamlOutputs{Probe: "output/falco.o"},
+	}
+	assert.Equal(t, []string{"output.probe"}, overlays.drift(drifted))
+
+	// No overlays, no drift
+	assert.Empty(t, Overlays(nil).drift(drifted))
+}
+
+func TestLoadOverlays(t *testing.T) {
+	repoRoot := t.TempDir()
+
+	// No overlays file in the repo root is fine
+	overlays, err := LoadOverlays("", repoRoot)
+	assert.NoError(t, err)
+	assert.Empty(t, overlays)
+
+	// An explicitly passed overlays file must exist
+	_, err = LoadOverlays(filepath.Join(repoRoot, "missing.yaml"), repoRoot)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(repoRoot, "driverkit"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(repoRoot, "driverkit", OverlaysFileName), []byte(testOverlays), 0o644))
+	overlays, err = LoadOverlays("", repoRoot)
+	assert.NoError(t, err)
+	assert.Len(t, overlays, 2)
+}
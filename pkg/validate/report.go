@@ -0,0 +1,365 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+)
+
+type ReportFormat string
+
+const (
+	ReportJSON  ReportFormat = "json"
+	ReportJUnit ReportFormat = "junit"
+	ReportSARIF ReportFormat = "sarif"
+
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	toolName     = "dbg-go"
+	toolURI      = "https://github.com/falcosecurity/dbg-go"
+
+	junitConsistencySuite = "consistency"
+)
+
+var SupportedReportFormats = []string{
+	string(ReportJSON),
+	string(ReportJUnit),
+	string(ReportSARIF),
+}
+
+// ReportSpec is a validation report to be written, in the given format, to the given path.
+type ReportSpec struct {
+	Format ReportFormat
+	Path   string
+}
+
+// ParseReports parses report specs, each in the "format=path" form.
+// Reports are never written to stdout, that is left to the command output and logs.
+func ParseReports(values []string) ([]ReportSpec, error) {
+	reports := make([]ReportSpec, 0, len(values))
+	for _, value := range values {
+		format, path, found := strings.Cut(value, "=")
+		if !found {
+			return nil, fmt.Errorf("report %q must be in the <format>=<path> form", value)
+		}
+		if !slices.Contains(SupportedReportFormats, format) {
+			return nil, fmt.Errorf("unsupported report format %q; supported: %s", format, strings.Join(SupportedReportFormats, ","))
+		}
+		// "-" would mean stdout, as for other outputs
+		if path == "" || path == "-" {
+			return nil, fmt.Errorf("report %q must be written to a file", value)
+		}
+		reports = append(reports, ReportSpec{Format: ReportFormat(format), Path: path})
+	}
+	return reports, nil
+}
+
+func (r ReportSpec) write(opts root.Options, configs []ValidatedConfig, consistency []ConsistencyFinding) error {
+	root.Printer.Logger.Info("writing validation report",
+		root.Printer.Logger.Args("format", r.Format, "path", r.Path))
+	if err := os.MkdirAll(filepath.Dir(r.Path), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := os.Create(r.Path)
+	if err != nil {
+		return err
+	}
+	err = r.render(f, opts, configs, consistency)
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+func (r ReportSpec) render(w io.Writer, opts root.Options, configs []ValidatedConfig, consistency []ConsistencyFinding) error {
+	switch r.Format {
+	case ReportJSON:
+		return renderJSONReport(w, configs, consistency)
+	case ReportJUnit:
+		return renderJUnitReport(w, opts, configs, consistency)
+	case ReportSARIF:
+		return renderSARIFReport(w, opts, configs, consistency)
+	default:
+		return fmt.Errorf("unsupported report format: %s", r.Format)
+	}
+}
+
+// reportPath returns the config path relative to the repo root, when possible,
+// so that CI can match it against the repository files.
+func reportPath(opts root.Options, path string) string {
+	if rel, err := filepath.Rel(opts.RepoRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	return filepath.ToSlash(path)
+}
+
+type reportSummary struct {
+	Configs  int `json:"configs"`
+	Invalid  int `json:"invalid"`
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+func summarize(configs []ValidatedConfig) reportSummary {
+	summary := reportSummary{Configs: len(configs)}
+	for _, config := range configs {
+		errs := config.count(SeverityError)
+		if errs > 0 {
+			summary.Invalid++
+		}
+		summary.Errors += errs
+		summary.Warnings += config.count(SeverityWarning)
+	}
+	return summary
+}
+
+type jsonReport struct {
+	Summary reportSummary `json:"summary"`
+	// Configs only lists configs with findings
+	Configs []ValidatedConfig `json:"configs"`
+	// Consistency lists cross-config findings, separately from per config ones
+	Consistency []ConsistencyFinding `json:"consistency,omitempty"`
+}
+
+func renderJSONReport(w io.Writer, configs []ValidatedConfig, consistency []ConsistencyFinding) error {
+	report := jsonReport{
+		Summary: summarize(configs),
+		Configs: slices.DeleteFunc(slices.Clone(configs), func(config ValidatedConfig) bool {
+			return len(config.Findings) == 0
+		}),
+		Consistency: consistency,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// renderJUnitReport renders a test suite for each driver version and architecture,
+// with a test case for each config; warnings are reported as test case output.
+// Consistency findings, never failing, are reported by a dedicated test suite.
+func renderJUnitReport(w io.Writer, opts root.Options, configs []ValidatedConfig, consistency []ConsistencyFinding) error {
+	report := junitTestSuites{Name: toolName + " configs validate"}
+	suites := make(map[string]*junitTestSuite)
+	for _, config := range configs {
+		suiteName := config.DriverVersion + "/" + config.Architecture.String()
+		suite, ok := suites[suiteName]
+		if !ok {
+			suite = &junitTestSuite{Name: suiteName}
+			suites[suiteName] = suite
+		}
+		testCase := junitTestCase{
+			Name:      filepath.Base(config.Path),
+			ClassName: suiteName,
+			File:      reportPath(opts, config.Path),
+		}
+		var failures, warnings []string
+		for _, finding := range config.Findings {
+			line := finding.Rule + ": " + finding.Message
+			if finding.Severity == SeverityError {
+				failures = append(failures, line)
+			} else {
+				warnings = append(warnings, line)
+			}
+		}
+		if len(failures) > 0 {
+			testCase.Failure = &junitFailure{
+				Type:    config.Findings[0].Rule,
+				Message: fmt.Sprintf("%d validation errors", len(failures)),
+				Text:    strings.Join(failures, "\n"),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		testCase.SystemOut = strings.Join(warnings, "\n")
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+		report.Tests++
+	}
+	for _, name := range slices.Sorted(maps.Keys(suites)) {
+		report.Suites = append(report.Suites, *suites[name])
+	}
+	if len(consistency) > 0 {
+		suite := junitTestSuite{Name: junitConsistencySuite}
+		for _, finding := range consistency {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      finding.Rule,
+				ClassName: junitConsistencySuite,
+				File:      finding.Configs[0],
+				SystemOut: finding.Message + "\n" + strings.Join(finding.Configs, "\n"),
+			})
+			suite.Tests++
+			report.Tests++
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// renderSARIFReport renders a SARIF log with a result for each finding, located at the config file;
+// consistency findings are warnings, located at each involved config.
+func renderSARIFReport(w io.Writer, opts root.Options, configs []ValidatedConfig, consistency []ConsistencyFinding) error {
+	ruleIDs := slices.Sorted(maps.Keys(ruleDescriptions))
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for _, id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: ruleDescriptions[id]}})
+	}
+	results := make([]sarifResult, 0)
+	for _, config := range configs {
+		for _, finding := range config.Findings {
+			results = append(results, sarifResult{
+				RuleID:    finding.Rule,
+				RuleIndex: slices.Index(ruleIDs, finding.Rule),
+				Level:     string(finding.Severity),
+				Message:   sarifMessage{Text: finding.Message},
+				Locations: []sarifLocation{{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: reportPath(opts, config.Path)},
+					},
+				}},
+			})
+		}
+	}
+	for _, finding := range consistency {
+		locations := make([]sarifLocation, 0, len(finding.Configs))
+		for _, config := range finding.Configs {
+			locations = append(locations, sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: config},
+				},
+			})
+		}
+		results = append(results, sarifResult{
+			RuleID:    finding.Rule,
+			RuleIndex: slices.Index(ruleIDs, finding.Rule),
+			Level:     string(SeverityWarning),
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: locations,
+		})
+	}
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: toolURI,
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
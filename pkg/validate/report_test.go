@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReports(t *testing.T) {
+	tests := map[string]struct {
+		values      []string
+		expectError bool
+		expected    []ReportSpec
+	}{
+		"unset": {
+			expected: []ReportSpec{},
+		},
+		"formats and paths": {
+			values: []string{"junit=out/junit.xml", "sarif=out/report.sarif"},
+			expected: []ReportSpec{
+				{Format: ReportJUnit, Path: "out/junit.xml"},
+				{Format: ReportSARIF, Path: "out/report.sarif"},
+			},
+		},
+		"missing path": {
+			values:      []string{"sarif"},
+			expectError: true,
+		},
+		"stdout": {
+			values:      []string{"json=-"},
+			expectError: true,
+		},
+		"unsupported format": {
+			values:      []string{"html=report.html"},
+			expectError: true,
+		},
+		"empty path": {
+			values:      []string{"json="},
+			expectError: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reports, err := ParseReports(test.values)
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, reports)
+		})
+	}
+}
+
+func TestValidateKeepGoingReports(t *testing.T) {
+	opts := root.Options{
+		RepoRoot:      t.TempDir(),
+		DriverName:    "falco",
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+		Jobs:          2,
+	}
+	writeConfig := func(name string, dkYaml DriverkitYaml) {
+		configPath := root.BuildConfigPath(opts, opts.DriverVersion[0], name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0o755))
+		cleanup, err := generateConfigFile(dkYaml, configPath)
+		assert.NoError(t, err)
+		t.Cleanup(cleanup)
+	}
+	valid := DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "centos", Architecture: "amd64"}
+	valid.FillOutputs(opts.DriverVersion[0], opts)
+	writeConfig("centos_5.10.0_1.yaml", valid)
+	// Wrong name and wrong arch
+	invalid := DriverkitYaml{KernelVersion: "1", KernelRelease: "5.11.0", Target: "centos", Architecture: "arm64"}
+	writeConfig("centos_5.11.0_2.yaml", invalid)
+	// Probe set on a kernel not supporting it
+	warned := DriverkitYaml{KernelVersion: "1", KernelRelease: "4.4.0", Target: "centos", Architecture: "amd64"}
+	warned.FillOutputs(opts.DriverVersion[0], opts)
+	warned.Output.Probe = "output/1.0.0+driver/x86_64/falco_centos_4.4.0_1.o"
+	writeConfig("centos_4.4.0_1.yaml", warned)
+	// Not base64 kernelconfigdata
+	notBase64 := valid
+	notBase64.KernelRelease = "5.12.0"
+	notBase64.FillOutputs(opts.DriverVersion[0], opts)
+	notBase64.KernelConfigData = "not base64!"
+	writeConfig("centos_5.12.0_1.yaml", notBase64)
+
+	reportsDir := t.TempDir()
+	validateOpts := Options{
+		Options:   opts,
+		KeepGoing: true,
+		Reports: []ReportSpec{
+			{Format: ReportJSON, Path: filepath.Join(reportsDir, "report.json")},
+			{Format: ReportJUnit, Path: filepath.Join(reportsDir, "junit.xml")},
+			{Format: ReportSARIF, Path: filepath.Join(reportsDir, "report.sarif")},
+		},
+	}
+	validateOpts.Result = root.NewResult()
+	err := Run(context.Background(), validateOpts)
+	assert.Error(t, err)
+
+	// Every config was validated
+	report := root.NewReport("validate", validateOpts.Options, err)
+	assert.Equal(t, 2, report.Summary[root.OutcomeSucceeded])
+	assert.Equal(t, 2, report.Summary[root.OutcomeFailed])
+
+	data, err := os.ReadFile(filepath.Join(reportsDir, "report.json"))
+	assert.NoError(t, err)
+	var jsonRep jsonReport
+	assert.NoError(t, json.Unmarshal(data, &jsonRep))
+	assert.Equal(t, reportSummary{Configs: 4, Invalid: 2, Errors: 3, Warnings: 1}, jsonRep.Summary)
+	assert.Len(t, jsonRep.Configs, 3)
+	rules := make(map[string]Severity)
+	for _, config := range jsonRep.Configs {
+		for _, finding := range config.Findings {
+			rules[finding.Rule] = finding.Severity
+		}
+	}
+	assert.Equal(t, map[string]Severity{
+		ruleWrongConfigName:           SeverityError,
+		ruleWrongArch:                 SeverityError,
+		ruleKernelConfigDataNotBase64: SeverityError,
+		ruleOutputOnUnsupportedKernel: SeverityWarning,
+	}, rules)
+
+	data, err = os.ReadFile(filepath.Join(reportsDir, "junit.xml"))
+	assert.NoError(t, err)
+	var junitRep junitTestSuites
+	assert.NoError(t, xml.Unmarshal(data, &junitRep))
+	assert.Equal(t, 4, junitRep.Tests)
+	assert.Equal(t, 2, junitRep.Failures)
+	assert.Len(t, junitRep.Suites, 1)
+	assert.Equal(t, "1.0.0+driver/amd64", junitRep.Suites[0].Name)
+	for _, testCase := range junitRep.Suites[0].TestCases {
+		assert.Equal(t, "driverkit/config/1.0.0+driver/x86_64/"+testCase.Name, testCase.File)
+	}
+
+	data, err = os.ReadFile(filepath.Join(reportsDir, "report.sarif"))
+	assert.NoError(t, err)
+	var sarifRep sarifLog
+	assert.NoError(t, json.Unmarshal(data, &sarifRep))
+	assert.Equal(t, sarifVersion, sarifRep.Version)
+	assert.Len(t, sarifRep.Runs, 1)
+	assert.Len(t, sarifRep.Runs[0].Results, 4)
+	for _, result := range sarifRep.Runs[0].Results {
+		assert.Equal(t, result.RuleID, sarifRep.Runs[0].Tool.Driver.Rules[result.RuleIndex].ID)
+		assert.Contains(t, []string{"error", "warning"}, result.Level)
+		assert.Regexp(t, `^driverkit/config/1\.0\.0\+driver/x86_64/centos_.+\.yaml$`, result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	}
+
+	// Without keep-going, the first invalid config stops the validation
+	validateOpts.KeepGoing = false
+	validateOpts.Reports = nil
+	validateOpts.Jobs = 1
+	validateOpts.Result = root.NewResult()
+	err = Run(context.Background(), validateOpts)
+	assert.Error(t, err)
+	report = root.NewReport("validate", validateOpts.Options, err)
+	assert.Equal(t, 1, report.Summary[root.OutcomeFailed])
+}
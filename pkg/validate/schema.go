@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
+	"github.com/falcosecurity/driverkit/pkg/kernelrelease"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+	schemaTitle     = "dbg driverkit config"
+)
+
+var (
+	// requiredFields must be set by every config.
+	requiredFields = []string{"target", "kernelrelease", "kernelversion", "architecture"}
+	// kernelConfigDataTargets are the targets driverkit cannot build drivers for without kernelconfigdata.
+	kernelConfigDataTargets = []string{
+		builder.TargetTypeFlatcar.String(),
+		builder.TargetTypeMinikube.String(),
+		builder.TargetTypeVanilla.String(),
+	}
+	// schemaDescriptions describe DriverkitYaml fields, keyed by their yaml path.
+	schemaDescriptions = map[string]string{
+		"kernelversion":    "Kernel version, ie: the build number of the kernel, as in uname -v.",
+		"kernelrelease":    "Kernel release, as in uname -r.",
+		"target":           "Driverkit target, eg: centos, or ubuntu-generic.",
+		"architecture":     "Architecture the drivers are built for.",
+		"output":           "Drivers to be built.",
+		"output.module":    "Kernel module output path, relative to the driverkit folder.",
+		"output.probe":     "eBPF probe output path, relative to the driverkit folder.",
+		"kernelurls":       "Kernel headers packages urls.",
+		"kernelconfigdata": "Base64 encoded kernel config.",
+	}
+)
+
+// decodeStrict decodes a config rejecting unknown fields; schema violations are returned as SchemaErr,
+// while the config is decoded anyway as far as possible.
+func decodeStrict(configPath string, configData []byte, dkYaml *DriverkitYaml) error {
+	dec := yaml.NewDecoder(bytes.NewReader(configData))
+	dec.KnownFields(true)
+	err := dec.Decode(dkYaml)
+	if errors.Is(err, io.EOF) {
+		// Empty config: required fields checks report it
+		return nil
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return &SchemaErr{configPath: configPath, errs: typeErr.Errors}
+	}
+	return err
+}
+
+// checkRequiredFields checks the fields required by every config, and by its target.
+func checkRequiredFields(configPath string, dkYaml DriverkitYaml, problems *configProblems) {
+	values := map[string]string{
+		"target":        dkYaml.Target,
+		"kernelrelease": dkYaml.KernelRelease,
+		"kernelversion": dkYaml.KernelVersion,
+		"architecture":  dkYaml.Architecture,
+	}
+	for _, field := range requiredFields {
+		if values[field] == "" {
+			problems.addError(&MissingFieldErr{configPath: configPath, field: field})
+		}
+	}
+	if dkYaml.Target == "" {
+		return
+	}
+	if _, err := builder.Factory(builder.Type(dkYaml.Target)); err != nil {
+		problems.addError(&UnknownTargetErr{configPath: configPath, target: dkYaml.Target})
+		return
+	}
+	if slices.Contains(kernelConfigDataTargets, dkYaml.Target) && dkYaml.KernelConfigData == "" {
+		problems.addError(&MissingFieldErr{configPath: configPath, field: "kernelconfigdata", target: dkYaml.Target})
+	}
+}
+
+// Schema returns the JSON Schema of driverkit configs, generated from the DriverkitYaml type,
+// eg: to be used by editors.
+func Schema() ([]byte, error) {
+	schema := typeSchema(reflect.TypeOf(DriverkitYaml{}), "")
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = schemaTitle
+	schema["required"] = requiredFields
+
+	properties := schema["properties"].(map[string]any)
+	properties["architecture"].(map[string]any)["enum"] = slices.Sorted(maps.Keys(kernelrelease.SupportedArchs))
+	properties["kernelconfigdata"].(map[string]any)["contentEncoding"] = "base64"
+	schema["allOf"] = []any{
+		map[string]any{
+			"if": map[string]any{
+				"properties": map[string]any{"target": map[string]any{"enum": kernelConfigDataTargets}},
+				"required":   []string{"target"},
+			},
+			"then": map[string]any{"required": []string{"kernelconfigdata"}},
+		},
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema returns the schema of a type, using yaml field names; path is the yaml path of the type.
+func typeSchema(t reflect.Type, path string) map[string]any {
+	schema := make(map[string]any)
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			properties[name] = typeSchema(field.Type, fieldPath)
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["additionalProperties"] = false
+	case reflect.Slice, reflect.Array:
+		schema["type"] = "array"
+		schema["items"] = typeSchema(t.Elem(), path+"[]")
+	case reflect.Bool:
+		schema["type"] = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema["type"] = "integer"
+	default:
+		schema["type"] = "string"
+	}
+	if description, ok := schemaDescriptions[path]; ok {
+		schema["description"] = description
+	}
+	return schema
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckConfigDataStrict(t *testing.T) {
+	opts := Options{Options: root.Options{
+		RepoRoot:      t.TempDir(),
+		DriverName:    "falco",
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver"},
+	}}
+	marshal := func(dkYaml DriverkitYaml) string {
+		dkYaml.FillOutputs(opts.DriverVersion[0], opts.Options)
+		data, err := yaml.Marshal(&dkYaml)
+		assert.NoError(t, err)
+		return string(data)
+	}
+	centos := DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "centos", Architecture: "amd64"}
+	vanilla := DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "vanilla", Architecture: "amd64"}
+	unknownTarget := DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "foo", Architecture: "amd64"}
+
+	tests := map[string]struct {
+		configName string
+		data       string
+		strict     bool
+		rules      []string
+	}{
+		"valid config": {
+			configName: "centos_5.10.0_1.yaml",
+			data:       marshal(centos),
+			strict:     true,
+		},
+		"unknown field not strict": {
+			configName: "centos_5.10.0_1.yaml",
+			data:       marshal(centos) + "kernelurl: foo\n",
+		},
+		"unknown field": {
+			configName: "centos_5.10.0_1.yaml",
+			data:       marshal(centos) + "kernelurl: foo\n",
+			strict:     true,
+			rules:      []string{ruleSchema},
+		},
+		"unknown nested field": {
+			configName: "centos_5.10.0_1.yaml",
+			data:       strings.Replace(marshal(centos), "output:\n", "output:\n    driver: foo\n", 1),
+			strict:     true,
+			rules:      []string{ruleSchema},
+		},
+		"missing kernelconfigdata": {
+			configName: "vanilla_5.10.0_1.yaml",
+			data:       marshal(vanilla),
+			strict:     true,
+			rules:      []string{ruleMissingField},
+		},
+		"unknown target": {
+			configName: "foo_5.10.0_1.yaml",
+			data:       marshal(unknownTarget),
+			strict:     true,
+			rules:      []string{ruleUnknownTarget},
+		},
+		"empty config": {
+			configName: "_.yaml",
+			data:       "",
+			strict:     true,
+			rules:      []string{ruleMissingField, ruleMissingField, ruleMissingField, ruleMissingField, ruleWrongConfigName, ruleWrongArch},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts.Strict = test.strict
+			configPath := root.BuildConfigPath(opts.Options, opts.DriverVersion[0], test.configName)
+			problems := checkConfigData(configPath, []byte(test.data), opts, opts.DriverVersion[0])
+			var rules []string
+			for _, finding := range problems.findings() {
+				if finding.Severity == SeverityError {
+					rules = append(rules, finding.Rule)
+				}
+			}
+			assert.Equal(t, test.rules, rules)
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	assert.NoError(t, err)
+
+	var schema struct {
+		Required             []string                   `json:"required"`
+		AdditionalProperties bool                       `json:"additionalProperties"`
+		Properties           map[string]json.RawMessage `json:"properties"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, requiredFields, schema.Required)
+	assert.False(t, schema.AdditionalProperties)
+
+	// Every DriverkitYaml field is part of the schema
+	dkType := reflect.TypeOf(DriverkitYaml{})
+	for i := 0; i < dkType.NumField(); i++ {
+		name, _, _ := strings.Cut(dkType.Field(i).Tag.Get("yaml"), ",")
+		assert.Contains(t, schema.Properties, name)
+	}
+	assert.Len(t, schema.Properties, dkType.NumField())
+}
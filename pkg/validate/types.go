@@ -15,6 +15,7 @@ limitations under the License.
 package validate
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -25,6 +26,21 @@ import (
 
 type Options struct {
 	root.Options
+	// Overlays, when set, are checked for drift.
+	Overlays Overlays
+	// KeepGoing validates all the configs, instead of stopping at the first invalid one.
+	KeepGoing bool
+	// Reports are written once all the configs are validated.
+	Reports []ReportSpec
+	// Fix rewrites, or renames, configs with mechanical errors; they are validated again afterwards.
+	Fix bool
+	// Strict rejects configs with unknown fields, or missing fields required by their target.
+	Strict bool
+	// Consistency runs cross-config checks once all the configs are validated; they only report warnings.
+	Consistency bool
+	// CheckURLs checks that kernelurls are reachable, issuing at most URLRate HEAD requests per second.
+	CheckURLs bool
+	URLRate   float64
 }
 
 type DriverkitYamlOutputs struct {
@@ -51,13 +67,18 @@ func (dy *DriverkitYaml) ToConfigName() string {
 	return fmt.Sprintf("%s.yaml", dy.ToName())
 }
 
-func (dy *DriverkitYaml) FillOutputs(driverVersion string, opts root.Options) {
+// configOutputPath returns the outputs path, without extension, as written in configs.
+func (dy *DriverkitYaml) configOutputPath(driverVersion string, opts root.Options) string {
 	outputPath := root.BuildOutputPath(opts, driverVersion, dy.ToName())
 	// Tricky because driverkit configs Outputs assume
 	// that the tool is called from the `driverkit` folder of test-infra repo.
 	// Only keep last 4 parts, ie: from "output/" onwards
 	paths := strings.Split(outputPath, "/")
-	configOutputPath := filepath.Join(paths[len(paths)-4:]...)
+	return filepath.Join(paths[len(paths)-4:]...)
+}
+
+func (dy *DriverkitYaml) FillOutputs(driverVersion string, opts root.Options) {
+	configOutputPath := dy.configOutputPath(driverVersion, opts)
 
 	kr := kernelrelease.FromString(dy.KernelRelease)
 	kr.Architecture = opts.Architecture
@@ -68,3 +89,16 @@ func (dy *DriverkitYaml) FillOutputs(driverVersion string, opts root.Options) {
 		dy.Output.Probe = configOutputPath + ".o"
 	}
 }
+
+// LeadingComments returns the comment lines at the top of a yaml document,
+// eg: hand written notes about the kernel, to be kept when rewriting it.
+func LeadingComments(yamlData []byte) []byte {
+	var comments []byte
+	for len(yamlData) > 0 && yamlData[0] == '#' {
+		line, rest, _ := bytes.Cut(yamlData, []byte("\n"))
+		comments = append(comments, line...)
+		comments = append(comments, '\n')
+		yamlData = rest
+	}
+	return comments
+}
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/falcosecurity/driverkit/pkg/driverbuilder/builder"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	urlCheckTimeout = 30 * time.Second
+	// urlCheckAttempts is the number of HEAD requests issued for an url, when failing with a transient error.
+	urlCheckAttempts = 2
+	// DefaultURLRate is the default number of HEAD requests per second issued by --check-urls.
+	DefaultURLRate = 10.0
+)
+
+// urlRetryBackoff is waited before retrying a transient failure; tests override it.
+var urlRetryBackoff = time.Second
+
+// urlCheck is the outcome of the HEAD request for an url; done is closed once it is available.
+// Canceled checks are not cached: waiters must check the url again.
+type urlCheck struct {
+	done     chan struct{}
+	err      error
+	canceled bool
+}
+
+// urlStatusErr is returned when the HEAD request got a response other than 200.
+type urlStatusErr struct {
+	status     string
+	statusCode int
+}
+
+func (u *urlStatusErr) Error() string {
+	return fmt.Sprintf("HEAD returned %s", u.status)
+}
+
+// transient returns whether the failure might not happen again, eg: a server error.
+func (u *urlStatusErr) transient() bool {
+	return u.statusCode >= http.StatusInternalServerError || u.statusCode == http.StatusTooManyRequests
+}
+
+// urlChecker checks kernelurls reachability, issuing a single, rate limited, HEAD request for each unique url.
+// Outcomes are cached for the whole validation, ie: across driver versions and architectures;
+// transient failures are retried once before being cached.
+type urlChecker struct {
+	client  *http.Client
+	limiter *rate.Limiter
+
+	mu    sync.Mutex
+	cache map[string]*urlCheck
+}
+
+func newURLChecker(requestsPerSecond float64) *urlChecker {
+	if requestsPerSecond <= 0 {
+		requestsPerSecond = DefaultURLRate
+	}
+	return &urlChecker{
+		client:  &http.Client{Timeout: urlCheckTimeout},
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), 1),
+		cache:   make(map[string]*urlCheck),
+	}
+}
+
+// check returns nil if the url is reachable; concurrent checks of the same url share the same request.
+func (c *urlChecker) check(ctx context.Context, u string) error {
+	u = resolveURL(u)
+	for {
+		c.mu.Lock()
+		check, ok := c.cache[u]
+		if !ok {
+			check = &urlCheck{done: make(chan struct{})}
+			c.cache[u] = check
+		}
+		c.mu.Unlock()
+
+		if !ok {
+			check.err = c.headWithRetry(ctx, u)
+			if ctx.Err() != nil {
+				// Only the caller was canceled: never cache it
+				check.canceled = true
+				c.mu.Lock()
+				delete(c.cache, u)
+				c.mu.Unlock()
+			}
+			close(check.done)
+		}
+		select {
+		case <-check.done:
+			if check.canceled && ctx.Err() == nil {
+				continue
+			}
+			return check.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// headWithRetry issues the HEAD request, retrying transient failures, eg: network errors.
+func (c *urlChecker) headWithRetry(ctx context.Context, u string) error {
+	var err error
+	for attempt := 1; attempt <= urlCheckAttempts; attempt++ {
+		err = c.head(ctx, u)
+		var statusErr *urlStatusErr
+		if err == nil || ctx.Err() != nil || (errors.As(err, &statusErr) && !statusErr.transient()) {
+			return err
+		}
+		if attempt < urlCheckAttempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(urlRetryBackoff):
+			}
+		}
+	}
+	return err
+}
+
+// head mimics driverkit, that only considers an url as resolving when HEAD returns 200.
+func (c *urlChecker) head(ctx context.Context, u string) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &urlStatusErr{status: resp.Status, statusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// resolveURL cleans any relative path element, that kernel-crawler does not resolve, as driverkit does.
+func resolveURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	return parsed.JoinPath().String()
+}
+
+// minimumURLs returns the number of resolving kernelurls required by the target builder.
+func minimumURLs(target string) int {
+	b, err := builder.Factory(builder.Type(target))
+	if err != nil {
+		return 1
+	}
+	if mb, ok := b.(builder.MinimumURLsBuilder); ok {
+		return mb.MinimumURLs()
+	}
+	return 1
+}
+
+// checkConfigURLs checks the reachability of the config kernelurls, adding any problem found.
+// Configs without kernelurls are skipped, since driverkit resolves them at build time.
+func checkConfigURLs(ctx context.Context, checker *urlChecker, configPath string, problems *configProblems) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		// Already reported by the config validation
+		return
+	}
+	var dkYaml DriverkitYaml
+	if err = yaml.Unmarshal(data, &dkYaml); err != nil || len(dkYaml.KernelUrls) == 0 {
+		return
+	}
+
+	unreachable := make([]string, len(dkYaml.KernelUrls))
+	g, gCtx := errgroup.WithContext(ctx)
+	for i, u := range dkYaml.KernelUrls {
+		g.Go(func() error {
+			if cErr := checker.check(gCtx, u); cErr != nil {
+				unreachable[i] = fmt.Sprintf("%s (%s)", u, cErr)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if ctx.Err() != nil {
+		return
+	}
+
+	var failed []string
+	for _, u := range unreachable {
+		if u != "" {
+			failed = append(failed, u)
+		}
+	}
+	reachable := len(dkYaml.KernelUrls) - len(failed)
+	minimum := minimumURLs(dkYaml.Target)
+	switch {
+	case reachable == 0:
+		problems.addError(&UnreachableKernelUrlsErr{configPath: configPath, urls: failed})
+	case reachable < minimum:
+		problems.addError(&TooFewKernelUrlsErr{configPath: configPath, target: dkYaml.Target, reachable: reachable, minimum: minimum, urls: failed})
+	case len(failed) > 0:
+		problems.addWarning(&UnreachableKernelUrlsWarn{configPath: configPath, urls: failed})
+	}
+	if len(failed) > 0 {
+		root.Printer.Logger.Debug("unreachable kernelurls",
+			root.Printer.Logger.Args("config", configPath, "urls", strings.Join(failed, ",")))
+	}
+}
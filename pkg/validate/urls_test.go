@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+/*
+Copyright (C) 2023 The Falco Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/falcosecurity/dbg-go/pkg/root"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCheckURLs(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path == "/dead.rpm" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	opts := root.Options{
+		RepoRoot:      t.TempDir(),
+		DriverName:    "falco",
+		Architecture:  "amd64",
+		DriverVersion: []string{"1.0.0+driver", "2.0.0+driver"},
+		Jobs:          4,
+	}
+	writeConfig := func(driverVersion string, dkYaml DriverkitYaml) {
+		dkYaml.FillOutputs(driverVersion, opts)
+		configPath := root.BuildConfigPath(opts, driverVersion, dkYaml.ToConfigName())
+		assert.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0o755))
+		cleanup, err := generateConfigFile(dkYaml, configPath)
+		assert.NoError(t, err)
+		t.Cleanup(cleanup)
+	}
+	for _, driverVersion := range opts.DriverVersion {
+		// All reachable, relative paths are resolved
+		writeConfig(driverVersion, DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0", Target: "centos", Architecture: "amd64",
+			KernelUrls: []string{server.URL + "/a/../ok.rpm"}})
+		// Some unreachable
+		writeConfig(driverVersion, DriverkitYaml{KernelVersion: "1", KernelRelease: "5.11.0", Target: "centos", Architecture: "amd64",
+			KernelUrls: []string{server.URL + "/ok.rpm", server.URL + "/dead.rpm"}})
+		// None reachable
+		writeConfig(driverVersion, DriverkitYaml{KernelVersion: "1", KernelRelease: "5.12.0", Target: "centos", Architecture: "amd64",
+			KernelUrls: []string{server.URL + "/dead.rpm"}})
+		// Debian requires at least 2 headers packages
+		writeConfig(driverVersion, DriverkitYaml{KernelVersion: "1", KernelRelease: "5.10.0-22-amd64", Target: "debian", Architecture: "amd64",
+			KernelUrls: []string{server.URL + "/ok.deb", server.URL + "/dead.rpm"}})
+		// No kernelurls, nothing to be checked
+		writeConfig(driverVersion, DriverkitYaml{KernelVersion: "1", KernelRelease: "5.13.0", Target: "centos", Architecture: "amd64"})
+	}
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	validateOpts := Options{
+		Options:   opts,
+		KeepGoing: true,
+		CheckURLs: true,
+		URLRate:   1000,
+		Reports:   []ReportSpec{{Format: ReportJSON, Path: reportPath}},
+	}
+	validateOpts.Result = root.NewResult()
+	assert.Error(t, Run(context.Background(), validateOpts))
+
+	// Each unique url is requested once, across driver versions
+	assert.Equal(t, map[string]int{"/ok.rpm": 1, "/dead.rpm": 1, "/ok.deb": 1}, hits)
+
+	data, err := os.ReadFile(reportPath)
+	assert.NoError(t, err)
+	var report jsonReport
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, reportSummary{Configs: 10, Invalid: 4, Errors: 4, Warnings: 2}, report.Summary)
+	findings := make(map[string]Finding)
+	for _, config := range report.Configs {
+		if config.DriverVersion == "1.0.0+driver" {
+			assert.Len(t, config.Findings, 1)
+			findings[filepath.Base(config.Path)] = Finding{Rule: config.Findings[0].Rule, Severity: config.Findings[0].Severity}
+		}
+	}
+	assert.Equal(t, map[string]Finding{
+		"centos_5.11.0_1.yaml":          {Rule: ruleUnreachableKernelUrls, Severity: SeverityWarning},
+		"centos_5.12.0_1.yaml":          {Rule: ruleUnreachableKernelUrls, Severity: SeverityError},
+		"debian_5.10.0-22-amd64_1.yaml": {Rule: ruleTooFewKernelUrls, Severity: SeverityError},
+	}, findings)
+}
+
+func TestURLCheckerTransientFailures(t *testing.T) {
+	backoff := urlRetryBackoff
+	urlRetryBackoff = time.Millisecond
+	t.Cleanup(func() {
+		urlRetryBackoff = backoff
+	})
+
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		hit := hits[r.URL.Path]
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/flaky.rpm":
+			if hit == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/down.rpm":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/dead.rpm":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checker := newURLChecker(1000)
+	ctx := context.Background()
+	// Transient failures are retried once
+	assert.NoError(t, checker.check(ctx, server.URL+"/flaky.rpm"))
+	assert.Error(t, checker.check(ctx, server.URL+"/down.rpm"))
+	// Not found is not retried
+	assert.Error(t, checker.check(ctx, server.URL+"/dead.rpm"))
+
+	// A canceled check is not cached
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, checker.check(canceledCtx, server.URL+"/ok.rpm"), context.Canceled)
+	assert.NoError(t, checker.check(ctx, server.URL+"/ok.rpm"))
+
+	// Outcomes are cached afterwards
+	for _, path := range []string{"/flaky.rpm", "/down.rpm", "/dead.rpm", "/ok.rpm"} {
+		_ = checker.check(ctx, server.URL+path)
+	}
+	assert.Equal(t, map[string]int{"/flaky.rpm": 2, "/down.rpm": 2, "/dead.rpm": 1, "/ok.rpm": 1}, hits)
+}
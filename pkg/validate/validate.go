@@ -15,7 +15,9 @@ limitations under the License.
 package validate
 
 import (
+	"context"
 	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -26,12 +28,81 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-func Run(opts Options) error {
+func Run(ctx context.Context, opts Options) error {
 	root.Printer.Logger.Info("validate config files")
+	collector := &findingsCollector{}
 	looper := root.NewFsLooper(root.BuildConfigPath)
-	return looper.LoopFiltered(opts.Options, "validating", "config", func(driverVersion, configPath string) error {
-		return validateConfig(configPath, opts, driverVersion)
+	var checker *urlChecker
+	if opts.CheckURLs {
+		checker = newURLChecker(opts.URLRate)
+	}
+	loopOpts := opts.Options
+	if opts.Fix {
+		// Validation is read only: in dry-run mode, configs are still validated, and fixes are just planned
+		loopOpts.DryRun = false
+	}
+	err := looper.LoopFiltered(ctx, loopOpts, "validating", "config", func(ctx context.Context, arch kernelrelease.Architecture, driverVersion, configPath string) error {
+		archOpts := opts
+		archOpts.Options = opts.ForArchitecture(arch)
+		problems := checkConfig(configPath, archOpts, driverVersion)
+		fixed := false
+		if opts.Fix && problems.fixable() {
+			var fixErr error
+			configPath, problems, fixErr = fixConfig(archOpts, driverVersion, configPath, problems)
+			if fixErr != nil {
+				problems.addError(fixErr)
+			} else {
+				fixed = true
+			}
+		}
+		if checker != nil {
+			checkConfigURLs(ctx, checker, configPath, problems)
+		}
+		collector.add(ValidatedConfig{
+			Path:          configPath,
+			Architecture:  arch,
+			DriverVersion: driverVersion,
+			Findings:      problems.findings(),
+			Fixed:         fixed,
+		})
+		err := problems.err()
+		if err != nil && opts.KeepGoing {
+			root.Printer.Logger.Error("invalid config",
+				root.Printer.Logger.Args("config", configPath, "err", err))
+			return &root.IgnoredErr{Err: err} // do not break the configs loop, just validate the next one
+		}
+		return err
 	})
+
+	validated := collector.validated()
+	var consistency []ConsistencyFinding
+	if err == nil && opts.Consistency {
+		// Only run once all the configs were validated, otherwise findings would be bogus
+		consistency = checkConsistency(opts.Options, loadConsistencyConfigs(validated))
+		for _, finding := range consistency {
+			root.Printer.Logger.Warn(finding.Message,
+				root.Printer.Logger.Args("rule", finding.Rule, "configs", strings.Join(finding.Configs, ",")))
+		}
+		opts.Result.SetDetails(consistencyDetails(consistency))
+	}
+	for _, report := range opts.Reports {
+		if rErr := report.write(opts.Options, validated, consistency); rErr != nil && err == nil {
+			err = rErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	invalid := 0
+	for _, config := range validated {
+		if config.count(SeverityError) > 0 {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d configs are invalid", invalid, len(validated))
+	}
+	return nil
 }
 
 func isBase64(s string) bool {
@@ -40,14 +111,37 @@ func isBase64(s string) bool {
 }
 
 func validateConfig(configPath string, opts Options, driverVersion string) error {
+	problems := checkConfig(configPath, opts, driverVersion)
+	return problems.err()
+}
+
+// checkConfig runs all the checks against a config, collecting all the errors and warnings found.
+func checkConfig(configPath string, opts Options, driverVersion string) *configProblems {
 	configData, err := os.ReadFile(configPath)
 	if err != nil {
-		return err
+		return &configProblems{errs: []error{err}}
 	}
+	return checkConfigData(configPath, configData, opts, driverVersion)
+}
+
+// checkConfigData runs all the checks against the content of the config at configPath.
+func checkConfigData(configPath string, configData []byte, opts Options, driverVersion string) *configProblems {
+	problems := &configProblems{}
 	var driverkitYaml DriverkitYaml
-	err = yaml.Unmarshal(configData, &driverkitYaml)
-	if err != nil {
-		return errors.WithMessagef(err, "config: %s", configPath)
+	if opts.Strict {
+		err := decodeStrict(configPath, configData, &driverkitYaml)
+		var schemaErr *SchemaErr
+		if err != nil && !errors.As(err, &schemaErr) {
+			problems.addError(errors.WithMessagef(err, "config: %s", configPath))
+			return problems
+		}
+		if schemaErr != nil {
+			problems.addError(schemaErr)
+		}
+		checkRequiredFields(configPath, driverkitYaml, problems)
+	} else if err := yaml.Unmarshal(configData, &driverkitYaml); err != nil {
+		problems.addError(errors.WithMessagef(err, "config: %s", configPath))
+		return problems
 	}
 
 	root.Printer.Logger.Info("validating",
@@ -61,12 +155,12 @@ func validateConfig(configPath string, opts Options, driverVersion string) error
 	expectedFilename := driverkitYaml.ToConfigName()
 	configFilename := filepath.Base(configPath)
 	if configFilename != expectedFilename {
-		return &WrongConfigNameErr{configFilename, expectedFilename}
+		problems.addError(&WrongConfigNameErr{configFilename, expectedFilename})
 	}
 
 	// Check that arch is ok
 	if driverkitYaml.Architecture != opts.Architecture.String() {
-		return &WrongArchInConfigErr{configPath, driverkitYaml.Architecture}
+		problems.addError(&WrongArchInConfigErr{configPath, driverkitYaml.Architecture})
 	}
 
 	outputPath := root.BuildOutputPath(opts.Options, driverVersion, driverkitYaml.ToName())
@@ -79,17 +173,20 @@ func validateConfig(configPath string, opts Options, driverVersion string) error
 	if driverkitYaml.Output.Probe != "" {
 		outputProbeFilename := filepath.Base(driverkitYaml.Output.Probe)
 		if outputProbeFilename != outputPathFilename+".o" {
-			return &WrongOutputProbeNameErr{outputProbeFilename, outputPathFilename}
+			problems.addError(&WrongOutputProbeNameErr{outputProbeFilename, outputPathFilename})
 		}
 
 		if !strings.Contains(driverkitYaml.Output.Probe, opts.Architecture.ToNonDeb()) {
-			return &WrongOutputProbeArchErr{driverkitYaml.Output.Probe, opts.Architecture.ToNonDeb()}
+			problems.addError(&WrongOutputProbeArchErr{driverkitYaml.Output.Probe, opts.Architecture.ToNonDeb()})
 		}
 
 		if !kr.SupportsProbe() {
 			// Not an error, just throw a warning
 			root.Printer.Logger.Warn("output probe set on an unsupported kernel release",
-				root.Printer.Logger.Args("kernelrelease", driverkitYaml.KernelRelease))
+				root.Printer.Logger.Args(
+					"config", configPath,
+					"kernelrelease", driverkitYaml.KernelRelease))
+			problems.addWarning(&OutputOnUnsupportedKernelWarn{configPath, driverkitYaml.KernelRelease, "probe"})
 		}
 	}
 
@@ -97,24 +194,32 @@ func validateConfig(configPath string, opts Options, driverVersion string) error
 	if driverkitYaml.Output.Module != "" {
 		outputModuleFilename := filepath.Base(driverkitYaml.Output.Module)
 		if outputModuleFilename != outputPathFilename+".ko" {
-			return &WrongOutputModuleNameErr{outputModuleFilename, outputPathFilename}
+			problems.addError(&WrongOutputModuleNameErr{outputModuleFilename, outputPathFilename})
 		}
 
 		if !strings.Contains(driverkitYaml.Output.Module, opts.Architecture.ToNonDeb()) {
-			return &WrongOutputModuleArchErr{driverkitYaml.Output.Module, opts.Architecture.ToNonDeb()}
+			problems.addError(&WrongOutputModuleArchErr{driverkitYaml.Output.Module, opts.Architecture.ToNonDeb()})
 		}
 
 		if !kr.SupportsModule() {
 			// Not an error, just throw a warning
 			root.Printer.Logger.Warn("output module set on an unsupported kernel release",
-				root.Printer.Logger.Args("kernelrelease", driverkitYaml.KernelRelease))
+				root.Printer.Logger.Args(
+					"config", configPath,
+					"kernelrelease", driverkitYaml.KernelRelease))
+			problems.addWarning(&OutputOnUnsupportedKernelWarn{configPath, driverkitYaml.KernelRelease, "module"})
 		}
 	}
 
 	// Kernelconfigdata, if present, must be base64 encoded
 	if len(driverkitYaml.KernelConfigData) > 0 && !isBase64(driverkitYaml.KernelConfigData) {
-		return &KernelConfigDataNotBase64Err{}
+		problems.addError(&KernelConfigDataNotBase64Err{})
 	}
 
-	return nil
+	// Overlays customisations must survive regeneration
+	if fields := opts.Overlays.drift(driverkitYaml); len(fields) > 0 {
+		problems.addError(&OverlayDriftErr{configPath, fields})
+	}
+
+	return problems
 }
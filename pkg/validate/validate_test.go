@@ -15,6 +15,7 @@ limitations under the License.
 package validate
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"strings"
@@ -56,6 +57,10 @@ func TestValidateConfig(t *testing.T) {
 			DriverName:    "TEST",
 		},
 	}
+	overlays, err := ParseOverlays([]byte("overlays:\n  - distro: centos\n    kernelconfigdata: Q09ORklHX1RFU1Q9eQo=\n    disableprobe: true"))
+	assert.NoError(t, err)
+	overlaysOpts := opts
+	overlaysOpts.Overlays = overlays
 	wrongArchOpts := Options{Options: root.Options{
 		Architecture:  "arm64",
 		DriverName:    "falco",
@@ -254,6 +259,36 @@ func TestValidateConfig(t *testing.T) {
 			confName:      "centos_5.10.0_1.yaml",
 			errorExpected: &KernelConfigDataNotBase64Err{},
 		},
+		"config matching overlays": {
+			opts: overlaysOpts,
+			dkConf: DriverkitYaml{
+				KernelVersion: "1",
+				KernelRelease: "5.10.0",
+				Target:        "centos",
+				Architecture:  "amd64",
+				Output: DriverkitYamlOutputs{
+					Module: root.BuildOutputPath(opts.Options, opts.DriverVersion[0], "centos_5.10.0_1.ko"),
+				},
+				KernelConfigData: "Q09ORklHX1RFU1Q9eQo=",
+			},
+			confName:      "centos_5.10.0_1.yaml",
+			errorExpected: nil,
+		},
+		"config drifted from overlays": {
+			opts: overlaysOpts,
+			dkConf: DriverkitYaml{
+				KernelVersion: "1",
+				KernelRelease: "5.10.0",
+				Target:        "centos",
+				Architecture:  "amd64",
+				Output: DriverkitYamlOutputs{
+					Module: root.BuildOutputPath(opts.Options, opts.DriverVersion[0], "centos_5.10.0_1.ko"),
+					Probe:  root.BuildOutputPath(opts.Options, opts.DriverVersion[0], "centos_5.10.0_1.o"),
+				},
+			},
+			confName:      "centos_5.10.0_1.yaml",
+			errorExpected: &OverlayDriftErr{},
+		},
 	}
 
 	for name, test := range tests {
@@ -454,7 +489,7 @@ func TestValidateConfigFiltered(t *testing.T) {
 			lines := 0
 			testutils.RunTestParsingLogs(t,
 				func() error {
-					return Run(test.opts)
+					return Run(context.Background(), test.opts)
 				},
 				func(line []byte) bool {
 					err = json.Unmarshal(line, &messageJSON)